@@ -1,17 +1,58 @@
 package config
 
 import (
-	"flag"
 	"fmt"
-
-	"github.com/caarlos0/env/v6"
+	"rates/internal/entity"
+	"strconv"
+	"strings"
+	"time"
 )
 
+// Config - настройки сервиса. Каждое поле задается переменной окружения из тега env,
+// ключом файла конфигурации с тем же именем в нижнем регистре (POLL_INTERVAL -> poll_interval)
+// и флагом командной строки (-poll-interval). Поля с тегом reload применяются по SIGHUP без перезапуска
 type Config struct {
-	LogLevel string `env:"LOG_LEVEL" envDefault:"DEBUG"`
+	LogLevel string `env:"LOG_LEVEL" envDefault:"DEBUG" reload:"true"`
+	// Общее время остановки всех компонентов
+	ShutdownTimeout time.Duration `env:"SHUTDOWN_TIMEOUT" envDefault:"15s"`
+	// Время завершения запросов gRPC сервером и шлюзом, после него соединения разрываются.
+	// Оставшееся время достается планировщику, базе данных и экспорту трасс
+	ServerStopTimeout time.Duration `env:"SERVER_STOP_TIMEOUT" envDefault:"5s"`
 
 	AppHost string `env:"APP_HOST" envDefault:"0.0.0.0"`
 	AppPort string `env:"APP_PORT" envDefault:"8080"`
+	// TLS gRPC сервера: без сертификата соединения не шифруются, TLS_CLIENT_CA_FILE включает mTLS.
+	// Файлы перечитываются при изменении с периодом TLS_RELOAD_INTERVAL
+	TLSCertFile       string        `env:"TLS_CERT_FILE"`
+	TLSKeyFile        string        `env:"TLS_KEY_FILE"`
+	TLSClientCAFile   string        `env:"TLS_CLIENT_CA_FILE"`
+	TLSReloadInterval time.Duration `env:"TLS_RELOAD_INTERVAL" envDefault:"30s"`
+
+	// REST/JSON шлюз к gRPC API, пустой порт отключает шлюз
+	GatewayHost string `env:"GATEWAY_HOST" envDefault:"0.0.0.0"`
+	GatewayPort string `env:"GATEWAY_PORT" envDefault:"8082"`
+	// Подключение шлюза к gRPC серверу при включенном TLS: центр сертификации сервера,
+	// клиентский сертификат для mTLS и имя сервера в сертификате, если оно отличается от APP_HOST
+	GatewayTLSCAFile     string `env:"GATEWAY_TLS_CA_FILE"`
+	GatewayTLSCertFile   string `env:"GATEWAY_TLS_CERT_FILE"`
+	GatewayTLSKeyFile    string `env:"GATEWAY_TLS_KEY_FILE"`
+	GatewayTLSServerName string `env:"GATEWAY_TLS_SERVER_NAME"`
+
+	// Проверка API ключа из метаданных x-api-key
+	AuthEnabled bool `env:"AUTH_ENABLED" envDefault:"false"`
+	// Хранилище ключей: db (таблица api_keys) или config (API_KEYS)
+	AuthKeyStore string `env:"AUTH_KEY_STORE" envDefault:"db"`
+	// Ключи в формате "client=key|rps|burst", квота ключа необязательна
+	APIKeys []string `env:"API_KEYS" envSeparator:","`
+	// Квота ключей без собственных значений, 0 снимает ограничение
+	AuthRateLimit float64       `env:"AUTH_RATE_LIMIT" envDefault:"10" reload:"true"`
+	AuthRateBurst int           `env:"AUTH_RATE_BURST" envDefault:"20" reload:"true"`
+	AuthCacheTTL  time.Duration `env:"AUTH_CACHE_TTL" envDefault:"1m"`
+	// Защита хранилища от перебора ключей: неизвестный ключ отклоняется без запроса в течение
+	// AUTH_NEGATIVE_CACHE_TTL, незнакомые ключи проверяются не чаще AUTH_FAILED_LOOKUP_RATE в секунду
+	AuthNegativeCacheTTL  time.Duration `env:"AUTH_NEGATIVE_CACHE_TTL" envDefault:"30s"`
+	AuthFailedLookupRate  float64       `env:"AUTH_FAILED_LOOKUP_RATE" envDefault:"10"`
+	AuthFailedLookupBurst int           `env:"AUTH_FAILED_LOOKUP_BURST" envDefault:"50"`
 
 	DbHost     string `env:"POSTGRES_HOST"`
 	DbPort     string `env:"POSTGRES_PORT"`
@@ -19,42 +60,181 @@ type Config struct {
 	DbName     string `env:"POSTGRES_DB"`
 	DbPassword string `env:"POSTGRES_PASSWORD"`
 
+	RateSource string   `env:"RATE_SOURCE" envDefault:"garantex"`
+	Markets    []string `env:"MARKETS" envSeparator:"," envDefault:"usdtrub" reload:"true"`
+	// Источники отдельных рынков в порядке опроса в формате "usdtrub=garantex,ethusdt=garantex|bybit"
+	MarketSources []string `env:"MARKET_SOURCES" envSeparator:","`
+	// Снимки старше MAX_QUOTE_AGE не сохраняются, 0 отключает проверку
+	MaxQuoteAge time.Duration `env:"MAX_QUOTE_AGE" envDefault:"1m"`
+	// Ограничение времени запроса к одному источнику вместе с повторами HTTP клиента:
+	// не меньше (HTTP_MAX_RETRIES+1)*HTTP_TIMEOUT + HTTP_MAX_RETRIES*HTTP_MAX_BACKOFF
+	SourceTimeout time.Duration `env:"SOURCE_TIMEOUT" envDefault:"8s"`
+
+	// HTTP клиент источников: таймаут запроса, повторы и предохранитель
+	HTTPTimeout         time.Duration `env:"HTTP_TIMEOUT" envDefault:"2s"`
+	HTTPMaxRetries      int           `env:"HTTP_MAX_RETRIES" envDefault:"2"`
+	HTTPBaseBackoff     time.Duration `env:"HTTP_BASE_BACKOFF" envDefault:"200ms"`
+	HTTPMaxBackoff      time.Duration `env:"HTTP_MAX_BACKOFF" envDefault:"1s"`
+	BreakerFailures     uint32        `env:"BREAKER_FAILURES" envDefault:"5"`
+	BreakerOpenInterval time.Duration `env:"BREAKER_OPEN_INTERVAL" envDefault:"30s"`
+
+	OrderBookLimit int `env:"ORDER_BOOK_LIMIT" envDefault:"50"`
+
+	// Источники сводного курса через запятую. Если не заданы, используется RATE_SOURCE
+	AggregateSources []string      `env:"AGGREGATE_SOURCES" envSeparator:","`
+	AggregateMaxAge  time.Duration `env:"AGGREGATE_MAX_AGE" envDefault:"30s"`
+	OutlierBps       int64         `env:"AGGREGATE_OUTLIER_BPS" envDefault:"200"`
+
+	CacheMaxAge time.Duration `env:"CACHE_MAX_AGE" envDefault:"2s"`
+
+	SubscriberBuffer int `env:"SUBSCRIBER_BUFFER" envDefault:"16"`
+	// Интервал опроса рынков с подписчиками при выключенном фоновом опросе
+	SubscribePollInterval time.Duration `env:"SUBSCRIBE_POLL_INTERVAL" envDefault:"2s"`
+
+	PollEnabled  bool          `env:"POLL_ENABLED" envDefault:"false"`
+	PollInterval time.Duration `env:"POLL_INTERVAL" envDefault:"10s" reload:"true"`
+	PollJitter   time.Duration `env:"POLL_JITTER" envDefault:"1s"`
+	// Интервалы опроса отдельных рынков в формате "btcrub=30s,ethusdt=1m"
+	PollIntervals []string `env:"POLL_INTERVALS" envSeparator:"," reload:"true"`
+
 	OTELExporterOTLPEndpoint string `env:"OTEL_EXPORTER_OTLP_ENDPOINT" envDefault:"http://localhost:4318"`
 
+	// Проверки зависимостей для grpc_health_v1, /healthz и /readyz. По каждому рынку должен быть стакан
+	// не старше HEALTH_SOURCE_MAX_AGE: при фоновом опросе значение должно превышать наибольший интервал опроса,
+	// без опроса устаревший рынок запрашивается у источника при проверке, поэтому HEALTH_TIMEOUT не меньше SOURCE_TIMEOUT
+	HealthInterval     time.Duration `env:"HEALTH_INTERVAL" envDefault:"10s"`
+	HealthTimeout      time.Duration `env:"HEALTH_TIMEOUT" envDefault:"8s"`
+	HealthSourceMaxAge time.Duration `env:"HEALTH_SOURCE_MAX_AGE" envDefault:"2m"`
+	// Рынки, от которых зависит готовность сервиса. Если не заданы, используется первый рынок из MARKETS.
+	// Отставание остальных рынков только отображается в отчете проверки markets
+	HealthMarkets []string `env:"HEALTH_MARKETS" envSeparator:","`
+
 	PrometheusHost string `env:"PROMETHEUS_HOST" envDefault:"0.0.0.0"`
 	PrometheusPort string `env:"PROMETHEUS_PORT" envDefault:"8081"`
-}
 
-func ReadConfig() (*Config, error) {
-	dbHost := flag.String("host", "", "Postgres host")
-	dbPort := flag.String("port", "", "Postgres port")
-	dbUser := flag.String("user", "", "Postgres user")
-	dbName := flag.String("dbname", "", "Postgres database name")
-	dbPassword := flag.String("password", "", "Postgres password")
-	flag.Parse()
+	// Служебные обработчики без аутентификации (/admin/log-level), по умолчанию доступны только локально.
+	// Пустой порт отключает сервер
+	AdminHost string `env:"ADMIN_HOST" envDefault:"127.0.0.1"`
+	AdminPort string `env:"ADMIN_PORT" envDefault:"8083"`
+}
 
-	config := Config{}
+// MarketList возвращает рынки из MARKETS в формате биржи без пустых значений и повторов
+func (c *Config) MarketList() []string {
+	return entity.NormalizeMarkets(c.Markets)
+}
 
-	err := env.Parse(&config)
-	if err != nil {
-		return nil, fmt.Errorf("read config error: %w", err)
+// HealthMarketList возвращает рынки из HEALTH_MARKETS в формате биржи или первый рынок из MARKETS
+func (c *Config) HealthMarketList() []string {
+	if markets := entity.NormalizeMarkets(c.HealthMarkets); len(markets) > 0 {
+		return markets
 	}
-	// Переопределение значений из флагов командной строки, если они заданы
-	if *dbHost != "" {
-		config.DbHost = *dbHost
+	markets := c.MarketList()
+	if len(markets) == 0 {
+		return nil
 	}
-	if *dbPort != "" {
-		config.DbPort = *dbPort
+	return markets[:1]
+}
+
+// MarketPollIntervals возвращает интервалы опроса по рынкам с учетом переопределений из POLL_INTERVALS
+func (c *Config) MarketPollIntervals() (map[string]time.Duration, error) {
+	markets := c.MarketList()
+	intervals := make(map[string]time.Duration, len(markets))
+	for _, market := range markets {
+		intervals[market] = c.PollInterval
 	}
-	if *dbUser != "" {
-		config.DbUser = *dbUser
+
+	overridden := make(map[string]bool, len(c.PollIntervals))
+	for _, item := range c.PollIntervals {
+		market, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid POLL_INTERVALS item %q: expected market=duration", item)
+		}
+		market = entity.NormalizeMarket(market)
+		if _, known := intervals[market]; !known {
+			return nil, fmt.Errorf("invalid POLL_INTERVALS item %q: market is not in MARKETS", item)
+		}
+		if overridden[market] {
+			return nil, fmt.Errorf("invalid POLL_INTERVALS item %q: duplicate market", item)
+		}
+		overridden[market] = true
+		interval, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("invalid POLL_INTERVALS item %q: %w", item, err)
+		}
+		intervals[market] = interval
 	}
-	if *dbName != "" {
-		config.DbName = *dbName
+	return intervals, nil
+}
+
+// MarketSourceNames возвращает списки источников по рынкам из MARKET_SOURCES.
+// Рынки без собственного списка используют RATE_SOURCE
+func (c *Config) MarketSourceNames() (map[string][]string, error) {
+	known := make(map[string]bool, len(c.Markets))
+	for _, market := range c.MarketList() {
+		known[market] = true
 	}
-	if *dbPassword != "" {
-		config.DbPassword = *dbPassword
+
+	sources := make(map[string][]string, len(c.MarketSources))
+	for _, item := range c.MarketSources {
+		market, value, ok := strings.Cut(item, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid MARKET_SOURCES item %q: expected market=source|source", item)
+		}
+		market = entity.NormalizeMarket(market)
+		if !known[market] {
+			return nil, fmt.Errorf("invalid MARKET_SOURCES item %q: market is not in MARKETS", item)
+		}
+		if _, exists := sources[market]; exists {
+			return nil, fmt.Errorf("invalid MARKET_SOURCES item %q: duplicate market", item)
+		}
+		var names []string
+		for _, name := range strings.Split(value, "|") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		if len(names) == 0 {
+			return nil, fmt.Errorf("invalid MARKET_SOURCES item %q: empty source list", item)
+		}
+		sources[market] = names
 	}
+	return sources, nil
+}
 
-	return &config, err
+// StaticAPIKeys возвращает ключи из API_KEYS по значению ключа
+func (c *Config) StaticAPIKeys() (map[string]entity.APIKey, error) {
+	keys := make(map[string]entity.APIKey, len(c.APIKeys))
+	for _, item := range c.APIKeys {
+		client, value, ok := strings.Cut(item, "=")
+		client = strings.TrimSpace(client)
+		if !ok || client == "" {
+			return nil, fmt.Errorf("invalid API_KEYS item for %q: expected client=key|rps|burst", client)
+		}
+		// Ключ не попадает в текст ошибок
+		parts := strings.Split(value, "|")
+		key := strings.TrimSpace(parts[0])
+		if key == "" || len(parts) > 3 {
+			return nil, fmt.Errorf("invalid API_KEYS item for %q: expected client=key|rps|burst", client)
+		}
+		if _, exists := keys[key]; exists {
+			return nil, fmt.Errorf("invalid API_KEYS item for %q: duplicate key", client)
+		}
+		apiKey := entity.APIKey{ClientID: client}
+		if len(parts) > 1 {
+			limit, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+			if err != nil || limit < 0 {
+				return nil, fmt.Errorf("invalid API_KEYS item for %q: bad rate limit", client)
+			}
+			apiKey.RateLimit = limit
+		}
+		if len(parts) > 2 {
+			burst, err := strconv.Atoi(strings.TrimSpace(parts[2]))
+			if err != nil || burst < 0 {
+				return nil, fmt.Errorf("invalid API_KEYS item for %q: bad burst", client)
+			}
+			apiKey.Burst = burst
+		}
+		keys[key] = apiKey
+	}
+	return keys, nil
 }
@@ -0,0 +1,198 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var requiredEnv = []string{"POSTGRES_HOST=db", "POSTGRES_PORT=5432", "POSTGRES_USER=postgres", "POSTGRES_DB=rates"}
+
+func writeFile(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, `
+log_level: INFO
+poll_interval: 20s
+poll_jitter: 2s
+markets: [usdtrub, btcrub]
+poll_intervals:
+  btcrub: 1m
+market_sources:
+  usdtrub: [garantex, backup]
+`)
+
+	environ := append([]string{"POLL_INTERVAL=30s", "POLL_JITTER=3s"}, requiredEnv...)
+	config, err := Load([]string{"-config", path, "-poll-interval=40s", "-host", "flaghost", "-poll-enabled"}, environ)
+	require.NoError(t, err)
+
+	// Флаг важнее окружения, окружение важнее файла, файл важнее значения по умолчанию
+	require.Equal(t, 40*time.Second, config.PollInterval)
+	require.Equal(t, 3*time.Second, config.PollJitter)
+	require.Equal(t, "INFO", config.LogLevel)
+	require.Equal(t, 8*time.Second, config.SourceTimeout)
+	require.Equal(t, "flaghost", config.DbHost)
+	require.True(t, config.PollEnabled)
+
+	require.Equal(t, []string{"usdtrub", "btcrub"}, config.Markets)
+	intervals, err := config.MarketPollIntervals()
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Duration{"usdtrub": 40 * time.Second, "btcrub": time.Minute}, intervals)
+	sources, err := config.MarketSourceNames()
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"usdtrub": {"garantex", "backup"}}, sources)
+}
+
+func TestLoadConfigFileFromEnv(t *testing.T) {
+	path := writeFile(t, "app_port: 9090\n")
+	config, err := Load(nil, append([]string{ConfigFileEnv + "=" + path}, requiredEnv...))
+	require.NoError(t, err)
+	require.Equal(t, "9090", config.AppPort)
+}
+
+func TestLoadExampleFile(t *testing.T) {
+	config, err := Load([]string{"-config", "../../config.example.yaml"}, nil)
+	require.NoError(t, err)
+	keys, err := config.StaticAPIKeys()
+	require.NoError(t, err)
+	require.Equal(t, "billing", keys["secret"].ClientID)
+}
+
+func TestLoadReportsAllErrors(t *testing.T) {
+	path := writeFile(t, `
+unknown_key: 1
+log_level: TRACE
+`)
+	_, err := Load([]string{"-config", path, "-gateway-port", "8080", "-http-max-retries", "many"}, []string{"MARKETS="})
+	require.Error(t, err)
+	for _, message := range []string{
+		`unknown key "unknown_key"`,
+		`"HTTPMaxRetries"`,
+		"LOG_LEVEL must be DEBUG or INFO",
+		"POSTGRES_HOST is required",
+		"APP_PORT and GATEWAY_PORT use the same port 8080",
+		"MARKETS must not be empty",
+	} {
+		require.Contains(t, err.Error(), message)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	config, err := Load(nil, requiredEnv)
+	require.NoError(t, err)
+
+	config.TLSKeyFile = "server.key"
+	config.AuthKeyStore = "redis"
+	config.HTTPMaxBackoff = time.Millisecond
+	config.PollIntervals = []string{"ethrub=1s"}
+	err = config.Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	require.Contains(t, err.Error(), "AUTH_KEY_STORE must be db or config")
+	require.Contains(t, err.Error(), "HTTP_MAX_BACKOFF")
+	require.Contains(t, err.Error(), "market is not in MARKETS")
+}
+
+func TestValidateSourceTimeout(t *testing.T) {
+	// Три попытки по 2s и две паузы до 1s не укладываются в 5s
+	_, err := Load([]string{"-source-timeout", "5s"}, requiredEnv)
+	require.ErrorContains(t, err, "SOURCE_TIMEOUT 5s is shorter than 8s")
+
+	_, err = Load([]string{"-source-timeout", "5s", "-http-timeout", "1s"}, requiredEnv)
+	require.NoError(t, err)
+}
+
+func TestValidateHealth(t *testing.T) {
+	// Без фонового опроса проверка ждет ответа источника
+	_, err := Load([]string{"-health-timeout", "2s"}, requiredEnv)
+	require.ErrorContains(t, err, "HEALTH_TIMEOUT 2s is shorter than SOURCE_TIMEOUT 8s")
+	_, err = Load([]string{"-health-timeout", "2s", "-poll-enabled"}, requiredEnv)
+	require.NoError(t, err)
+
+	_, err = Load([]string{"-health-markets", "btcrub"}, requiredEnv)
+	require.ErrorContains(t, err, `HEALTH_MARKETS item "btcrub" is not in MARKETS`)
+
+	config, err := Load([]string{"-markets", "usdtrub,btcrub"}, requiredEnv)
+	require.NoError(t, err)
+	require.Equal(t, []string{"usdtrub"}, config.HealthMarketList())
+	config, err = Load([]string{"-markets", "usdtrub,btcrub", "-health-markets", "BTC-RUB"}, requiredEnv)
+	require.NoError(t, err)
+	require.Equal(t, []string{"btcrub"}, config.HealthMarketList())
+}
+
+func TestValidateServerStopTimeout(t *testing.T) {
+	// Остановка gRPC сервера и шлюза не должна занимать все время остановки
+	_, err := Load([]string{"-shutdown-timeout", "8s"}, requiredEnv)
+	require.ErrorContains(t, err, "SERVER_STOP_TIMEOUT 5s must be less than half of SHUTDOWN_TIMEOUT 8s")
+
+	_, err = Load([]string{"-shutdown-timeout", "8s", "-server-stop-timeout", "3s"}, requiredEnv)
+	require.NoError(t, err)
+}
+
+func TestLoaderReloadsFile(t *testing.T) {
+	path := writeFile(t, "log_level: DEBUG\npoll_interval: 10s\n")
+	environ := append([]string{ConfigFileEnv + "=" + path, "POLL_JITTER=3s"}, requiredEnv...)
+	loader := NewLoader(nil, environ)
+	current, err := loader.Load()
+	require.NoError(t, err)
+
+	// Окружение запуска задано, но значения из файла перечитываются
+	require.NoError(t, os.WriteFile(path, []byte("log_level: INFO\npoll_interval: 20s\npoll_jitter: 5s\n"), 0o600))
+	next, err := loader.Load()
+	require.NoError(t, err)
+	require.Equal(t, "INFO", next.LogLevel)
+	require.Equal(t, 20*time.Second, next.PollInterval)
+	// Окружение запуска по-прежнему важнее файла
+	require.Equal(t, 3*time.Second, next.PollJitter)
+	require.ElementsMatch(t, []string{"LOG_LEVEL", "POLL_INTERVAL"}, Diff(current, next).Reloadable)
+}
+
+func TestDiff(t *testing.T) {
+	current, err := Load(nil, requiredEnv)
+	require.NoError(t, err)
+	next, err := Load([]string{"-log-level", "INFO", "-markets", "usdtrub,btcrub", "-app-port", "9090"}, requiredEnv)
+	require.NoError(t, err)
+
+	require.True(t, Diff(current, current).Empty())
+
+	changes := Diff(current, next)
+	require.Equal(t, []string{"LOG_LEVEL", "MARKETS"}, changes.Reloadable)
+	require.Equal(t, []string{"APP_PORT"}, changes.Restart)
+	require.True(t, changes.Changed("APP_PORT"))
+	require.False(t, changes.Changed("POLL_INTERVAL"))
+
+	// Поля, требующие перезапуска, остаются прежними
+	reloaded := Reloaded(current, next)
+	require.Equal(t, "INFO", reloaded.LogLevel)
+	require.Equal(t, []string{"usdtrub", "btcrub"}, reloaded.Markets)
+	require.Equal(t, "8080", reloaded.AppPort)
+	require.Equal(t, "DEBUG", current.LogLevel)
+}
+
+func TestMarketNormalization(t *testing.T) {
+	config, err := Load([]string{
+		"-markets", "BTC-RUB, btcrub,usdtrub",
+		"-poll-intervals", "btcrub=30s",
+		"-market-sources", " usdt_rub =garantex",
+	}, requiredEnv)
+	require.NoError(t, err)
+
+	require.Equal(t, []string{"btcrub", "usdtrub"}, config.MarketList())
+	intervals, err := config.MarketPollIntervals()
+	require.NoError(t, err)
+	require.Equal(t, map[string]time.Duration{"btcrub": 30 * time.Second, "usdtrub": 10 * time.Second}, intervals)
+	sources, err := config.MarketSourceNames()
+	require.NoError(t, err)
+	require.Equal(t, map[string][]string{"usdtrub": {"garantex"}}, sources)
+
+	config.PollIntervals = []string{"BTC-RUB=1m", "btcrub=2m"}
+	_, err = config.MarketPollIntervals()
+	require.ErrorContains(t, err, "duplicate market")
+}
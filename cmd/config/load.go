@@ -0,0 +1,282 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/caarlos0/env/v6"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileEnv - переменная окружения с путем к файлу конфигурации, флаг -config имеет приоритет
+const ConfigFileEnv = "CONFIG_FILE"
+
+// legacyFlags - короткие флаги базы данных, оставленные для совместимости
+var legacyFlags = map[string]string{
+	"host":     "POSTGRES_HOST",
+	"port":     "POSTGRES_PORT",
+	"user":     "POSTGRES_USER",
+	"dbname":   "POSTGRES_DB",
+	"password": "POSTGRES_PASSWORD",
+}
+
+// field - описание настройки, полученное из тегов Config
+type field struct {
+	env          string
+	defaultValue string
+	isBool       bool
+}
+
+func fields() []field {
+	t := reflect.TypeOf(Config{})
+	result := make([]field, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("env"), ",")
+		if name == "" {
+			continue
+		}
+		result = append(result, field{
+			env:          name,
+			defaultValue: t.Field(i).Tag.Get("envDefault"),
+			isBool:       t.Field(i).Type.Kind() == reflect.Bool,
+		})
+	}
+	return result
+}
+
+// FileKey возвращает ключ файла конфигурации для переменной окружения
+func FileKey(envName string) string {
+	return strings.ToLower(envName)
+}
+
+// FlagName возвращает флаг командной строки для переменной окружения
+func FlagName(envName string) string {
+	return strings.ReplaceAll(strings.ToLower(envName), "_", "-")
+}
+
+// Loader читает настройки с флагами и окружением, зафиксированными при запуске.
+// При перезагрузке перечитывается только файл конфигурации: окружение процесса после запуска не меняется
+type Loader struct {
+	args    []string
+	environ []string
+}
+
+// NewLoader запоминает флаги и окружение запуска
+func NewLoader(args, environ []string) *Loader {
+	return &Loader{
+		args:    append([]string(nil), args...),
+		environ: append([]string(nil), environ...),
+	}
+}
+
+// Load перечитывает файл конфигурации и собирает настройки с флагами и окружением запуска
+func (l *Loader) Load() (*Config, error) {
+	return Load(l.args, l.environ)
+}
+
+// Load собирает настройки с приоритетом флаги > окружение > файл > значения по умолчанию
+// и возвращает все найденные ошибки разом
+func Load(args, environ []string) (*Config, error) {
+	flagValues, configFile, err := parseFlags(args)
+	if err != nil {
+		return nil, err
+	}
+
+	envValues := make(map[string]string, len(environ))
+	for _, item := range environ {
+		if key, value, ok := strings.Cut(item, "="); ok {
+			envValues[key] = value
+		}
+	}
+	if configFile == "" {
+		configFile = envValues[ConfigFileEnv]
+	}
+
+	var errs []error
+	merged := make(map[string]string)
+	if configFile != "" {
+		fileValues, err := readFile(configFile)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		for key, value := range fileValues {
+			merged[key] = value
+		}
+	}
+	for key, value := range envValues {
+		merged[key] = value
+	}
+	for key, value := range flagValues {
+		merged[key] = value
+	}
+
+	config := Config{}
+	if err := env.Parse(&config, env.Options{Environment: merged}); err != nil {
+		errs = append(errs, err)
+	}
+	if err := config.Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return &config, nil
+}
+
+// flagValue запоминает, был ли флаг задан явно
+type flagValue struct {
+	value  string
+	set    bool
+	isBool bool
+}
+
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(value string) error {
+	v.value, v.set = value, true
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool {
+	return v.isBool
+}
+
+// parseFlags возвращает явно заданные флаги по именам переменных окружения и путь к файлу конфигурации
+func parseFlags(args []string) (map[string]string, string, error) {
+	fs := flag.NewFlagSet("rates", flag.ContinueOnError)
+	configFile := fs.String("config", "", "Path to YAML config file, also "+ConfigFileEnv)
+
+	values := make(map[string]*flagValue)
+	for _, f := range fields() {
+		value := &flagValue{isBool: f.isBool}
+		values[f.env] = value
+		usage := "env " + f.env
+		if f.defaultValue != "" {
+			usage += fmt.Sprintf(" (default %q)", f.defaultValue)
+		}
+		fs.Var(value, FlagName(f.env), usage)
+	}
+	for name, envName := range legacyFlags {
+		fs.Var(values[envName], name, "Alias of -"+FlagName(envName))
+	}
+
+	if err := fs.Parse(args); err != nil {
+		return nil, "", err
+	}
+	if fs.NArg() > 0 {
+		return nil, "", fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	result := make(map[string]string)
+	for envName, value := range values {
+		if value.set {
+			result[envName] = value.value
+		}
+	}
+	return result, *configFile, nil
+}
+
+// readFile читает плоский YAML файл и приводит значения к формату переменных окружения.
+// Списки объединяются через запятую, словари - в элементы key=value, списки внутри словаря - через |
+func readFile(path string) (map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("config file: %w", err)
+	}
+	defer file.Close()
+
+	var raw map[string]interface{}
+	if err := yaml.NewDecoder(file).Decode(&raw); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	known := make(map[string]string)
+	for _, f := range fields() {
+		known[FileKey(f.env)] = f.env
+	}
+
+	var errs []error
+	values := make(map[string]string, len(raw))
+	for key, value := range raw {
+		envName, ok := known[key]
+		if !ok {
+			errs = append(errs, fmt.Errorf("config file %s: unknown key %q", path, key))
+			continue
+		}
+		text, err := fileValue(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("config file %s: %s: %w", path, key, err))
+			continue
+		}
+		values[envName] = text
+	}
+	return values, errors.Join(errs...)
+}
+
+func fileValue(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case []interface{}:
+		return joinScalars(v, ",")
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		items := make([]string, 0, len(v))
+		for _, key := range keys {
+			var (
+				item string
+				err  error
+			)
+			if list, ok := v[key].([]interface{}); ok {
+				item, err = joinScalars(list, "|")
+			} else {
+				item, err = scalar(v[key])
+			}
+			if err != nil {
+				return "", err
+			}
+			items = append(items, key+"="+item)
+		}
+		return strings.Join(items, ","), nil
+	default:
+		return scalar(v)
+	}
+}
+
+func joinScalars(values []interface{}, sep string) (string, error) {
+	items := make([]string, 0, len(values))
+	for _, value := range values {
+		item, err := scalar(value)
+		if err != nil {
+			return "", err
+		}
+		items = append(items, item)
+	}
+	return strings.Join(items, sep), nil
+}
+
+func scalar(value interface{}) (string, error) {
+	switch v := value.(type) {
+	case []interface{}, map[string]interface{}:
+		return "", errors.New("nested values are not supported")
+	case nil:
+		return "", nil
+	default:
+		return fmt.Sprint(v), nil
+	}
+}
@@ -0,0 +1,61 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Changes - поля, изменившиеся при перезагрузке, по именам переменных окружения
+type Changes struct {
+	// Reloadable применяются без перезапуска
+	Reloadable []string
+	// Restart вступят в силу только после перезапуска
+	Restart []string
+}
+
+// Empty сообщает, что настройки не изменились
+func (c Changes) Empty() bool {
+	return len(c.Reloadable) == 0 && len(c.Restart) == 0
+}
+
+// Changed сообщает, что поле с переменной окружения envName изменилось
+func (c Changes) Changed(envName string) bool {
+	for _, name := range append(c.Reloadable, c.Restart...) {
+		if name == envName {
+			return true
+		}
+	}
+	return false
+}
+
+// Diff сравнивает текущие настройки с перечитанными
+func Diff(current, next *Config) Changes {
+	var changes Changes
+	t := reflect.TypeOf(Config{})
+	currentValue, nextValue := reflect.ValueOf(current).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if reflect.DeepEqual(currentValue.Field(i).Interface(), nextValue.Field(i).Interface()) {
+			continue
+		}
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("env"), ",")
+		if t.Field(i).Tag.Get("reload") == "true" {
+			changes.Reloadable = append(changes.Reloadable, name)
+		} else {
+			changes.Restart = append(changes.Restart, name)
+		}
+	}
+	return changes
+}
+
+// Reloaded возвращает копию current с полями из next, которые применяются без перезапуска
+func Reloaded(current, next *Config) *Config {
+	reloaded := *current
+	t := reflect.TypeOf(Config{})
+	value, nextValue := reflect.ValueOf(&reloaded).Elem(), reflect.ValueOf(next).Elem()
+	for i := 0; i < t.NumField(); i++ {
+		if t.Field(i).Tag.Get("reload") == "true" {
+			value.Field(i).Set(nextValue.Field(i))
+		}
+	}
+	return &reloaded
+}
@@ -0,0 +1,163 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"rates/internal/entity"
+	"rates/pkg/logger"
+)
+
+// Validate проверяет настройки и возвращает все найденные ошибки разом
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+	positive := func(name string, value time.Duration) {
+		check(value > 0, "%s must be positive, got %s", name, value)
+	}
+	notNegative := func(name string, value time.Duration) {
+		check(value >= 0, "%s must not be negative, got %s", name, value)
+	}
+
+	level := strings.ToUpper(c.LogLevel)
+	check(level == logger.LevelDebug || level == logger.LevelInfo,
+		"LOG_LEVEL must be %s or %s, got %q", logger.LevelDebug, logger.LevelInfo, c.LogLevel)
+
+	check(c.DbHost != "", "POSTGRES_HOST is required")
+	check(c.DbPort != "", "POSTGRES_PORT is required")
+	check(c.DbUser != "", "POSTGRES_USER is required")
+	check(c.DbName != "", "POSTGRES_DB is required")
+
+	// Порты должны быть корректными и не пересекаться, пустые GATEWAY_PORT и ADMIN_PORT отключают сервер
+	ports := map[string]string{}
+	for _, p := range []struct {
+		name, value string
+		optional    bool
+	}{
+		{"APP_PORT", c.AppPort, false},
+		{"GATEWAY_PORT", c.GatewayPort, true},
+		{"PROMETHEUS_PORT", c.PrometheusPort, false},
+		{"ADMIN_PORT", c.AdminPort, true},
+		{"POSTGRES_PORT", c.DbPort, true},
+	} {
+		if p.value == "" {
+			check(p.optional, "%s is required", p.name)
+			continue
+		}
+		port, err := strconv.Atoi(p.value)
+		if err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("%s must be a port number, got %q", p.name, p.value))
+			continue
+		}
+		if p.name == "POSTGRES_PORT" {
+			continue
+		}
+		if other, ok := ports[p.value]; ok {
+			errs = append(errs, fmt.Errorf("%s and %s use the same port %s", other, p.name, p.value))
+			continue
+		}
+		ports[p.value] = p.name
+	}
+
+	check((c.TLSCertFile == "") == (c.TLSKeyFile == ""), "TLS_CERT_FILE and TLS_KEY_FILE must be set together")
+	check(c.TLSClientCAFile == "" || c.TLSCertFile != "", "TLS_CLIENT_CA_FILE requires TLS_CERT_FILE")
+	check((c.GatewayTLSCertFile == "") == (c.GatewayTLSKeyFile == ""),
+		"GATEWAY_TLS_CERT_FILE and GATEWAY_TLS_KEY_FILE must be set together")
+	if c.TLSCertFile != "" {
+		positive("TLS_RELOAD_INTERVAL", c.TLSReloadInterval)
+	}
+
+	switch c.AuthKeyStore {
+	case "db":
+	case "config":
+		if _, err := c.StaticAPIKeys(); err != nil {
+			errs = append(errs, err)
+		}
+	default:
+		errs = append(errs, fmt.Errorf("AUTH_KEY_STORE must be db or config, got %q", c.AuthKeyStore))
+	}
+	check(c.AuthRateLimit >= 0, "AUTH_RATE_LIMIT must not be negative, got %v", c.AuthRateLimit)
+	check(c.AuthRateBurst >= 0, "AUTH_RATE_BURST must not be negative, got %d", c.AuthRateBurst)
+	notNegative("AUTH_CACHE_TTL", c.AuthCacheTTL)
+	notNegative("AUTH_NEGATIVE_CACHE_TTL", c.AuthNegativeCacheTTL)
+	check(c.AuthFailedLookupRate >= 0, "AUTH_FAILED_LOOKUP_RATE must not be negative, got %v", c.AuthFailedLookupRate)
+	check(c.AuthFailedLookupBurst >= 0, "AUTH_FAILED_LOOKUP_BURST must not be negative, got %d",
+		c.AuthFailedLookupBurst)
+
+	check(c.RateSource != "", "RATE_SOURCE is required")
+	check(len(c.Markets) > 0, "MARKETS must not be empty")
+	for _, market := range c.Markets {
+		check(entity.NormalizeMarket(market) != "", "MARKETS must not contain empty items")
+	}
+	if _, err := c.MarketSourceNames(); err != nil {
+		errs = append(errs, err)
+	}
+	if intervals, err := c.MarketPollIntervals(); err != nil {
+		errs = append(errs, err)
+	} else if c.PollEnabled {
+		for market, interval := range intervals {
+			check(interval > 0, "poll interval of market %s must be positive, got %s", market, interval)
+		}
+	}
+	notNegative("POLL_JITTER", c.PollJitter)
+
+	positive("SHUTDOWN_TIMEOUT", c.ShutdownTimeout)
+	positive("SERVER_STOP_TIMEOUT", c.ServerStopTimeout)
+	// gRPC сервер и шлюз останавливаются по очереди, остальным компонентам должно остаться время
+	check(2*c.ServerStopTimeout < c.ShutdownTimeout, "SERVER_STOP_TIMEOUT %s must be less than half of "+
+		"SHUTDOWN_TIMEOUT %s", c.ServerStopTimeout, c.ShutdownTimeout)
+	positive("SOURCE_TIMEOUT", c.SourceTimeout)
+	positive("HTTP_TIMEOUT", c.HTTPTimeout)
+	notNegative("MAX_QUOTE_AGE", c.MaxQuoteAge)
+	check(c.HTTPMaxRetries >= 0, "HTTP_MAX_RETRIES must not be negative, got %d", c.HTTPMaxRetries)
+	notNegative("HTTP_BASE_BACKOFF", c.HTTPBaseBackoff)
+	check(c.HTTPMaxBackoff >= c.HTTPBaseBackoff, "HTTP_MAX_BACKOFF %s is less than HTTP_BASE_BACKOFF %s",
+		c.HTTPMaxBackoff, c.HTTPBaseBackoff)
+	positive("BREAKER_OPEN_INTERVAL", c.BreakerOpenInterval)
+	// Иначе последний повтор HTTP клиента всегда прерывается сроком запроса к источнику.
+	// Пауза перед повтором с Retry-After может достигать HTTP_MAX_BACKOFF
+	if c.HTTPMaxRetries >= 0 {
+		retries := time.Duration(c.HTTPMaxRetries)
+		worst := (retries+1)*c.HTTPTimeout + retries*c.HTTPMaxBackoff
+		check(c.SourceTimeout >= worst, "SOURCE_TIMEOUT %s is shorter than %s needed for HTTP_MAX_RETRIES %d "+
+			"with HTTP_TIMEOUT %s and HTTP_MAX_BACKOFF %s", c.SourceTimeout, worst, c.HTTPMaxRetries,
+			c.HTTPTimeout, c.HTTPMaxBackoff)
+	}
+
+	check(c.OrderBookLimit > 0, "ORDER_BOOK_LIMIT must be positive, got %d", c.OrderBookLimit)
+	check(c.SubscriberBuffer > 0, "SUBSCRIBER_BUFFER must be positive, got %d", c.SubscriberBuffer)
+	positive("SUBSCRIBE_POLL_INTERVAL", c.SubscribePollInterval)
+	positive("AGGREGATE_MAX_AGE", c.AggregateMaxAge)
+	check(c.OutlierBps >= 0, "AGGREGATE_OUTLIER_BPS must not be negative, got %d", c.OutlierBps)
+	notNegative("CACHE_MAX_AGE", c.CacheMaxAge)
+
+	positive("HEALTH_INTERVAL", c.HealthInterval)
+	positive("HEALTH_TIMEOUT", c.HealthTimeout)
+	positive("HEALTH_SOURCE_MAX_AGE", c.HealthSourceMaxAge)
+	// Без фонового опроса проверка запрашивает устаревший рынок у источника и не должна прерывать запрос раньше него
+	check(c.PollEnabled || c.HealthTimeout >= c.SourceTimeout, "HEALTH_TIMEOUT %s is shorter than SOURCE_TIMEOUT %s "+
+		"while POLL_ENABLED is false", c.HealthTimeout, c.SourceTimeout)
+	markets := make(map[string]bool)
+	for _, market := range c.MarketList() {
+		markets[market] = true
+	}
+	for _, market := range entity.NormalizeMarkets(c.HealthMarkets) {
+		check(markets[market], "HEALTH_MARKETS item %q is not in MARKETS", market)
+	}
+
+	if c.OTELExporterOTLPEndpoint != "" {
+		endpoint, err := url.Parse(c.OTELExporterOTLPEndpoint)
+		check(err == nil && endpoint.Scheme != "" && endpoint.Host != "",
+			"OTEL_EXPORTER_OTLP_ENDPOINT must be an absolute URL, got %q", c.OTELExporterOTLPEndpoint)
+	}
+
+	return errors.Join(errs...)
+}
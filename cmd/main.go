@@ -2,26 +2,35 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
+	"flag"
 	"fmt"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"rates/cmd/config"
+	"rates/internal/auth"
 	"rates/internal/controller"
+	"rates/internal/infrastructure/healthcheck"
+	"rates/internal/infrastructure/httpclient"
 	"rates/internal/infrastructure/metrics"
 	"rates/internal/infrastructure/optel.go"
+	pb "rates/internal/infrastructure/pb"
 	"rates/internal/infrastructure/server"
 	"rates/internal/repository"
+	"rates/internal/scheduler"
 	"rates/internal/service"
+	"rates/internal/source"
+	"rates/pkg/lifecycle"
 	"rates/pkg/logger"
-	"sync"
 	"syscall"
 	"time"
 
 	"github.com/pressly/goose"
 	"go.uber.org/zap"
-	"google.golang.org/grpc/health"
-	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var (
@@ -29,11 +38,15 @@ var (
 )
 
 func main() {
-	var wg sync.WaitGroup
-
-	configs, err := config.ReadConfig()
+	// Флаги и окружение фиксируются при запуске, при перезагрузке перечитывается файл конфигурации
+	loader := config.NewLoader(os.Args[1:], os.Environ())
+	configs, err := loader.Load()
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
 	if err != nil {
-		panic(err)
+		fmt.Fprintf(os.Stderr, "invalid configuration:\n%s\n", err)
+		os.Exit(2)
 	}
 
 	logger.BuildLogger(configs.LogLevel)
@@ -41,77 +54,405 @@ func main() {
 
 	ctx := context.Background()
 
+	// Компоненты запускаются в порядке добавления и останавливаются в обратном:
+	// проверка здоровья, серверы, опрос, база данных и последней трассировка
+	app := lifecycle.New(configs.ShutdownTimeout)
+
+	var otelShutdown func(context.Context) error
+	app.Append(lifecycle.Hook{
+		Name: "otel",
+		Start: func(ctx context.Context) error {
+			var err error
+			otelShutdown, err = optel.SetUpOTelSDK(ctx, configs.OTELExporterOTLPEndpoint)
+			return err
+		},
+		Stop: func(ctx context.Context) error {
+			return otelShutdown(ctx)
+		},
+	})
+
 	db, err := repository.NewPostgresClient(configs.DbHost, configs.DbPort, configs.DbUser,
 		configs.DbPassword, configs.DbName)
 	if err != nil {
 		log.Fatal(err)
 	}
+	app.Append(lifecycle.Hook{
+		Name: "postgres",
+		Stop: func(context.Context) error {
+			return db.Close()
+		},
+	})
 
 	if err := goose.Up(db, "migrations"); err != nil {
 		log.Fatalf("error db migrate: %s", err)
 	}
 
+	// Каждый источник получает собственный HTTP клиент, чтобы предохранитель одной биржи не отключал другие
+	sources := make(map[string]source.RateSource)
+	newSource := func(name string) (source.RateSource, error) {
+		if src, ok := sources[name]; ok {
+			return src, nil
+		}
+		client := httpclient.New(httpclient.Config{
+			Name:            name,
+			Timeout:         configs.HTTPTimeout,
+			MaxRetries:      configs.HTTPMaxRetries,
+			BaseBackoff:     configs.HTTPBaseBackoff,
+			MaxBackoff:      configs.HTTPMaxBackoff,
+			BreakerFailures: configs.BreakerFailures,
+			BreakerTimeout:  configs.BreakerOpenInterval,
+		})
+		src, err := source.New(name, client)
+		if err != nil {
+			return nil, err
+		}
+		sources[name] = src
+		return src, nil
+	}
+
+	primary, err := newSource(configs.RateSource)
+	if err != nil {
+		log.Fatalf("error create rate source: %s", err)
+	}
+	src := source.NewFailover(configs.SourceTimeout, primary)
+
+	// Резервные источники по рынкам опрашиваются по порядку
+	sourceNames, err := configs.MarketSourceNames()
+	if err != nil {
+		log.Fatalf("error read market sources: %s", err)
+	}
+	marketSources := make(map[string]source.RateSource, len(sourceNames))
+	for market, names := range sourceNames {
+		chain := make([]source.RateSource, 0, len(names))
+		for _, name := range names {
+			marketSource, err := newSource(name)
+			if err != nil {
+				log.Fatalf("error create rate source for %s: %s", market, err)
+			}
+			chain = append(chain, marketSource)
+		}
+		marketSources[market] = source.NewFailover(configs.SourceTimeout, chain...)
+	}
+
+	aggSources := make([]source.RateSource, 0, len(configs.AggregateSources))
+	for _, name := range configs.AggregateSources {
+		aggSource, err := newSource(name)
+		if err != nil {
+			log.Fatalf("error create aggregate source: %s", err)
+		}
+		aggSources = append(aggSources, aggSource)
+	}
+
 	repo := repository.NewRepository(db)
-	service := service.NewService(repo)
-	contrll := controller.NewController(service)
-	server := server.NewServer(contrll)
+	svc := service.NewService(repo, src, service.Config{
+		Markets:               configs.MarketList(),
+		OrderBookLimit:        configs.OrderBookLimit,
+		ServeFromStore:        configs.PollEnabled,
+		SubscriberBuffer:      configs.SubscriberBuffer,
+		SubscribePollInterval: configs.SubscribePollInterval,
+		MarketSources:         marketSources,
+		MaxQuoteAge:           configs.MaxQuoteAge,
+		AggregateSources:      aggSources,
+		AggregateMaxAge:       configs.AggregateMaxAge,
+		OutlierBps:            configs.OutlierBps,
+	})
 
-	grpcServer := server.RunApp(configs.AppHost, configs.AppPort)
+	var servicer controller.Servicer = svc
+	var collector scheduler.Collector = svc
+	// Кэш последних котировок перед источником
+	if configs.CacheMaxAge > 0 {
+		cached := service.NewCachedService(svc, configs.CacheMaxAge)
+		servicer, collector = cached, cached
+	}
 
-	// Проверка здоровья gRPC сервера
-	healthServer := health.NewServer()
-	grpc_health_v1.RegisterHealthServer(grpcServer, healthServer)
-	healthServer.SetServingStatus("GetRatesUSDT", grpc_health_v1.HealthCheckResponse_SERVING)
+	// TLS включается сертификатом сервера, сертификаты перечитываются без перезапуска
+	var serverTLS, gatewayTLS *tls.Config
+	if configs.TLSCertFile != "" || configs.TLSKeyFile != "" {
+		reloader, err := server.NewCertReloader(server.TLSConfig{
+			CertFile:       configs.TLSCertFile,
+			KeyFile:        configs.TLSKeyFile,
+			CAFile:         configs.TLSClientCAFile,
+			ReloadInterval: configs.TLSReloadInterval,
+		})
+		if err != nil {
+			log.Fatalf("error load server certificates: %s", err)
+		}
+		serverTLS = reloader.ServerConfig()
 
-	go func() {
-		err := metrics.Listen(fmt.Sprintf("%s:%s", configs.PrometheusHost, configs.PrometheusPort))
+		gatewayReloader, err := server.NewCertReloader(server.TLSConfig{
+			CertFile:       configs.GatewayTLSCertFile,
+			KeyFile:        configs.GatewayTLSKeyFile,
+			CAFile:         configs.GatewayTLSCAFile,
+			ReloadInterval: configs.TLSReloadInterval,
+		})
 		if err != nil {
-			log.Error("error listen metrics: %w", err)
+			log.Fatalf("error load gateway certificates: %s", err)
 		}
-	}()
+		app.Append(lifecycle.Hook{
+			Name: "tls reload",
+			Start: func(ctx context.Context) error {
+				go reloader.Run(ctx)
+				go gatewayReloader.Run(ctx)
+				return nil
+			},
+		})
+		serverName := configs.GatewayTLSServerName
+		if serverName == "" {
+			serverName = dialHost(configs.AppHost)
+		}
+		gatewayTLS = gatewayReloader.ClientConfig(serverName)
+	}
 
-	otelShutdown, err := optel.SetUpOTelSDK(ctx)
-	if err != nil {
-		log.Fatalf("error start tracer: %s", err)
+	// Проверка API ключей и квоты клиентов
+	var authenticator *auth.Authenticator
+	if configs.AuthEnabled {
+		var store auth.KeyStore
+		switch configs.AuthKeyStore {
+		case "db":
+			store = repo
+		case "config":
+			keys, err := configs.StaticAPIKeys()
+			if err != nil {
+				log.Fatalf("error read api keys: %s", err)
+			}
+			store = auth.NewStaticStore(keys)
+		default:
+			log.Fatalf("unknown AUTH_KEY_STORE %q: expected db or config", configs.AuthKeyStore)
+		}
+		authenticator = auth.NewAuthenticator(store, auth.Config{
+			RateLimit:         configs.AuthRateLimit,
+			Burst:             configs.AuthRateBurst,
+			CacheTTL:          configs.AuthCacheTTL,
+			NegativeCacheTTL:  configs.AuthNegativeCacheTTL,
+			FailedLookupRate:  configs.AuthFailedLookupRate,
+			FailedLookupBurst: configs.AuthFailedLookupBurst,
+		})
 	}
 
-	defer func() {
-		err := errors.Join(err, otelShutdown(ctx))
-		log.Error("error stop service: %w", err)
-	}()
+	contrll := controller.NewController(servicer)
+	srv := server.NewServer(contrll, authenticator)
+
+	// Проверка здоровья: API готов к запросам, пока доступны база данных и источник
+	checks := []healthcheck.Check{
+		{Name: "postgres", Critical: true, Probe: healthcheck.PingProbe(db)},
+	}
+	// Готовность зависит только от рынков HEALTH_MARKETS, отставание остальных отображается в проверке markets
+	checks = append(checks,
+		healthcheck.Check{Name: "source", Critical: true, Probe: func(ctx context.Context) error {
+			return svc.CheckSources(ctx, configs.HealthMarketList(), configs.HealthSourceMaxAge)
+		}},
+		healthcheck.Check{Name: "markets", Probe: func(ctx context.Context) error {
+			return svc.CheckSources(ctx, svc.Markets(), configs.HealthSourceMaxAge)
+		}})
+	if otelAddr, err := endpointAddress(configs.OTELExporterOTLPEndpoint); err != nil {
+		log.Warnf("OTel exporter health check is disabled: %s", err)
+	} else {
+		checks = append(checks, healthcheck.Check{Name: "otel", Probe: healthcheck.DialProbe(otelAddr)})
+	}
+	checker := healthcheck.New(srv.Health(), healthcheck.Config{
+		Interval: configs.HealthInterval,
+		Timeout:  configs.HealthTimeout,
+		Services: []string{"", "GetRatesUSDT", pb.GetRateser_ServiceDesc.ServiceName},
+	}, checks...)
 
-	sigs := make(chan os.Signal, 1)
-	signal.Notify(sigs, syscall.SIGTERM, syscall.SIGINT)
+	// Сервер метрик останавливается после API, чтобы /readyz отвечал во время остановки
+	metricsServer := &http.Server{
+		Handler: metrics.Handler(map[string]http.Handler{
+			"/healthz": checker.LiveHandler(),
+			"/readyz":  checker.ReadyHandler(),
+		}),
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	app.Append(app.ServeHook("metrics", net.JoinHostPort(configs.PrometheusHost, configs.PrometheusPort),
+		metricsServer.Serve, lifecycle.ShutdownHTTP(metricsServer)))
 
-	wg.Add(1)
-	go func() {
-		defer wg.Done()
+	// Служебные обработчики слушают отдельный порт, по умолчанию только на loopback
+	if configs.AdminPort != "" {
+		adminMux := http.NewServeMux()
+		adminMux.Handle("/admin/log-level", logger.LevelHandler())
+		adminServer := &http.Server{Handler: adminMux, ReadHeaderTimeout: 5 * time.Second}
+		app.Append(app.ServeHook("admin", net.JoinHostPort(configs.AdminHost, configs.AdminPort),
+			adminServer.Serve, lifecycle.ShutdownHTTP(adminServer)))
+	}
 
-		<-sigs
-		log.Info("Received termination signal, shutting down...")
+	// Фоновый опрос рынков по расписанию
+	var sched *scheduler.Scheduler
+	if configs.PollEnabled {
+		jobs, err := pollJobs(configs)
+		if err != nil {
+			log.Fatalf("error read poll intervals: %s", err)
+		}
+		sched = scheduler.New(collector, jobs, configs.PollJitter)
+		app.Append(lifecycle.Hook{
+			Name: "scheduler",
+			Start: func(ctx context.Context) error {
+				sched.Start(ctx)
+				return nil
+			},
+			Stop: func(context.Context) error {
+				sched.Stop()
+				return nil
+			},
+		})
+	}
 
-		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
-		defer shutdownCancel()
+	// Перезагрузка настроек по SIGHUP. Применяются поля с тегом reload, об остальных изменениях только сообщается
+	current := configs
+	applyConfig := func(next *config.Config) error {
+		changes := config.Diff(current, next)
+		if changes.Empty() {
+			log.Info("Configuration reloaded: no changes")
+			return nil
+		}
+		if changes.Changed("POLL_INTERVAL") || changes.Changed("POLL_INTERVALS") || changes.Changed("MARKETS") {
+			jobs, err := pollJobs(next)
+			if err != nil {
+				return err
+			}
+			if sched != nil {
+				sched.Update(jobs)
+			}
+		}
+		if changes.Changed("LOG_LEVEL") {
+			if err := logger.SetLevel(next.LogLevel); err != nil {
+				return err
+			}
+		}
+		if changes.Changed("MARKETS") {
+			svc.SetMarkets(next.MarketList())
+		}
+		if authenticator != nil && (changes.Changed("AUTH_RATE_LIMIT") || changes.Changed("AUTH_RATE_BURST")) {
+			authenticator.SetQuota(next.AuthRateLimit, next.AuthRateBurst)
+		}
+		if len(changes.Restart) > 0 {
+			log.Warnw("Configuration changes require restart", "fields", changes.Restart)
+		}
+		log.Infow("Configuration reloaded", "applied", changes.Reloadable)
 
-		end := make(chan struct{})
+		current = config.Reloaded(current, next)
+		return nil
+	}
+	reloads := make(chan os.Signal, 1)
+	app.Append(lifecycle.Hook{
+		Name: "config reload",
+		Start: func(ctx context.Context) error {
+			signal.Notify(reloads, syscall.SIGHUP)
+			go func() {
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-reloads:
+					}
+					next, err := loader.Load()
+					if err == nil {
+						err = applyConfig(next)
+					}
+					if err != nil {
+						metrics.CountConfigReload("error")
+						log.Errorf("Configuration reload failed, keeping current settings: %s", err)
+						continue
+					}
+					metrics.CountConfigReload("success")
+				}
+			}()
+			return nil
+		},
+		Stop: func(context.Context) error {
+			signal.Stop(reloads)
+			return nil
+		},
+	})
 
-		go func() {
-			grpcServer.GracefulStop()
-			end <- struct{}{}
-			close(end)
-		}()
+	// Серверы получают отдельный срок остановки, чтобы зависшие соединения не забирали время
+	// остановки планировщика, базы данных и экспорта трасс
+	grpcServer := srv.GRPCServer(serverTLS)
+	grpcHook := app.ServeHook("grpc", net.JoinHostPort(configs.AppHost, configs.AppPort),
+		grpcServer.Serve, server.GracefulStop(grpcServer))
+	grpcHook.Timeout = configs.ServerStopTimeout
+	app.Append(grpcHook)
 
-		select {
-		case <-end:
-			log.Infof("Shutting down gracefully...")
-			return
-		case <-shutdownCtx.Done():
-			grpcServer.Stop()
-			log.Infof("Server stop whith time limit")
-			return
+	// Шлюз останавливается раньше gRPC сервера, чтобы не проксировать запросы в останавливаемый сервер
+	if configs.GatewayPort != "" {
+		gateway, err := srv.Gateway(ctx, net.JoinHostPort(dialHost(configs.AppHost), configs.AppPort), gatewayTLS)
+		if err != nil {
+			log.Fatalf("error create gateway: %s", err)
 		}
-	}()
+		gatewayHook := app.ServeHook("gateway", net.JoinHostPort(configs.GatewayHost, configs.GatewayPort),
+			gateway.Serve, lifecycle.ShutdownHTTP(gateway))
+		gatewayHook.Timeout = configs.ServerStopTimeout
+		app.Append(gatewayHook)
+	}
 
-	wg.Wait()
+	// Рынки с подписчиками опрашиваются через кэш. Потоки подписок закрываются до остановки серверов,
+	// иначе серверы ждут их до отключения клиентов
+	app.Append(lifecycle.Hook{
+		Name: "subscriptions",
+		Start: func(ctx context.Context) error {
+			svc.StartSubscriptions(ctx, collector)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			svc.Close()
+			return nil
+		},
+	})
 
+	// Проверка здоровья останавливается первой: балансировщики перестают направлять запросы до остановки серверов
+	app.Append(lifecycle.Hook{
+		Name: "health",
+		Start: func(ctx context.Context) error {
+			go checker.Run(ctx)
+			return nil
+		},
+		Stop: func(context.Context) error {
+			checker.Shutdown()
+			return nil
+		},
+	})
+
+	if err := app.Run(ctx, syscall.SIGTERM, syscall.SIGINT); err != nil {
+		log.Errorf("error stop service: %s", err)
+		os.Exit(1)
+	}
+	log.Info("Service stopped")
+}
+
+// pollJobs возвращает расписание опроса рынков
+func pollJobs(configs *config.Config) ([]scheduler.Job, error) {
+	intervals, err := configs.MarketPollIntervals()
+	if err != nil {
+		return nil, err
+	}
+	jobs := make([]scheduler.Job, 0, len(intervals))
+	for market, interval := range intervals {
+		jobs = append(jobs, scheduler.Job{Market: market, Interval: interval})
+	}
+	return jobs, nil
+}
+
+// dialHost возвращает адрес для подключения к серверу, слушающему на host
+func dialHost(host string) string {
+	if ip := net.ParseIP(host); host == "" || ip != nil && ip.IsUnspecified() {
+		return "localhost"
+	}
+	return host
+}
+
+// endpointAddress возвращает host:port из URL экспортера
+func endpointAddress(endpoint string) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("no host in %q", endpoint)
+	}
+	if u.Port() != "" {
+		return u.Host, nil
+	}
+	if u.Scheme == "https" {
+		return net.JoinHostPort(u.Hostname(), "443"), nil
+	}
+	return net.JoinHostPort(u.Hostname(), "80"), nil
 }
@@ -0,0 +1,248 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"rates/internal/entity"
+	"rates/internal/repository"
+	"rates/pkg/logger"
+
+	"golang.org/x/time/rate"
+)
+
+var (
+	log = logger.Logger().Named("auth").Sugar()
+)
+
+var (
+	ErrMissingKey    = errors.New("missing api key")
+	ErrInvalidKey    = errors.New("invalid api key")
+	ErrQuotaExceeded = errors.New("api key quota exceeded")
+	// ErrTooManyFailures возвращается для незнакомых ключей, пока исчерпан лимит неудачных проверок
+	ErrTooManyFailures = errors.New("too many invalid api keys")
+)
+
+// maxInvalidKeys ограничивает размер кэша неизвестных ключей
+const maxInvalidKeys = 10_000
+
+// KeyStore ищет действующий API ключ по sha256 хэшу. Неизвестный ключ - repository.ErrNotFound
+type KeyStore interface {
+	APIKeyByHash(ctx context.Context, keyHash string) (entity.APIKey, error)
+}
+
+// HashKey возвращает sha256 ключа в hex, в котором ключи хранятся в api_keys
+func HashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+// StaticStore - ключи из конфигурации, хранятся по хэшу
+type StaticStore map[string]entity.APIKey
+
+// NewStaticStore создает хранилище из ключей в открытом виде
+func NewStaticStore(keys map[string]entity.APIKey) StaticStore {
+	store := make(StaticStore, len(keys))
+	for key, apiKey := range keys {
+		store[HashKey(key)] = apiKey
+	}
+	return store
+}
+
+func (s StaticStore) APIKeyByHash(ctx context.Context, keyHash string) (entity.APIKey, error) {
+	key, ok := s[keyHash]
+	if !ok {
+		return entity.APIKey{}, fmt.Errorf("api key: %w", repository.ErrNotFound)
+	}
+	return key, nil
+}
+
+type Config struct {
+	// Квота ключей без собственных значений, RateLimit <= 0 снимает ограничение
+	RateLimit float64
+	Burst     int
+	// Время, в течение которого ключ не перечитывается из хранилища
+	CacheTTL time.Duration
+	// Время, в течение которого неизвестный ключ отклоняется без обращения к хранилищу
+	NegativeCacheTTL time.Duration
+	// Частота неудачных проверок незнакомых ключей по всем клиентам. После исчерпания
+	// незнакомые ключи отклоняются без обращения к хранилищу, FailedLookupRate <= 0 снимает ограничение
+	FailedLookupRate  float64
+	FailedLookupBurst int
+}
+
+// Authenticator проверяет API ключи и ведет квоту запросов каждого ключа
+type Authenticator struct {
+	store KeyStore
+
+	mu   sync.Mutex
+	cfg  Config
+	keys map[string]*cachedKey
+	// Хэши неизвестных ключей и время, до которого они отклоняются без обращения к хранилищу
+	invalid map[string]time.Time
+	// nil - без ограничения неудачных проверок
+	failures *rate.Limiter
+
+	now func() time.Time
+}
+
+type cachedKey struct {
+	key     entity.APIKey
+	limiter *rate.Limiter
+	expires time.Time
+}
+
+func NewAuthenticator(store KeyStore, cfg Config) *Authenticator {
+	var failures *rate.Limiter
+	if cfg.FailedLookupRate > 0 {
+		failures = rate.NewLimiter(rate.Limit(cfg.FailedLookupRate), max(cfg.FailedLookupBurst, 1))
+	}
+	return &Authenticator{
+		store:    store,
+		cfg:      cfg,
+		keys:     make(map[string]*cachedKey),
+		invalid:  make(map[string]time.Time),
+		failures: failures,
+		now:      time.Now,
+	}
+}
+
+// Authenticate проверяет ключ и расходует один запрос из его квоты.
+// При превышении квоты возвращает ErrQuotaExceeded и время, через которое запрос будет разрешен
+func (a *Authenticator) Authenticate(ctx context.Context, key string) (entity.APIKey, time.Duration, error) {
+	if key == "" {
+		return entity.APIKey{}, 0, ErrMissingKey
+	}
+
+	cached, delay, err := a.lookup(ctx, HashKey(key))
+	if err != nil {
+		return entity.APIKey{}, delay, err
+	}
+
+	now := a.now()
+	reservation := cached.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		return cached.key, 0, fmt.Errorf("%w: client %s", ErrQuotaExceeded, cached.key.ClientID)
+	}
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		reservation.CancelAt(now)
+		return cached.key, delay, fmt.Errorf("%w: client %s", ErrQuotaExceeded, cached.key.ClientID)
+	}
+	return cached.key, 0, nil
+}
+
+// lookup возвращает ключ из кэша или хранилища. Если хранилище недоступно, используется устаревшая запись кэша.
+// Незнакомые ключи проверяются в хранилище не чаще лимита неудачных проверок
+func (a *Authenticator) lookup(ctx context.Context, keyHash string) (cachedKey, time.Duration, error) {
+	now := a.now()
+	a.mu.Lock()
+	cached, ok := a.keys[keyHash]
+	var snapshot cachedKey
+	if ok {
+		snapshot = *cached
+	} else {
+		if expires, invalid := a.invalid[keyHash]; invalid && now.Before(expires) {
+			a.mu.Unlock()
+			return cachedKey{}, 0, ErrInvalidKey
+		}
+		if a.failures != nil && a.failures.TokensAt(now) < 1 {
+			reservation := a.failures.ReserveN(now, 1)
+			delay := reservation.DelayFrom(now)
+			reservation.CancelAt(now)
+			a.mu.Unlock()
+			return cachedKey{}, delay, ErrTooManyFailures
+		}
+	}
+	a.mu.Unlock()
+	if ok && now.Before(snapshot.expires) {
+		return snapshot, 0, nil
+	}
+
+	key, err := a.store.APIKeyByHash(ctx, keyHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		a.rememberInvalid(keyHash)
+		return cachedKey{}, 0, ErrInvalidKey
+	}
+	if err != nil {
+		if ok {
+			log.Warnf("Failed to refresh api key of client %s, using cached: %v", snapshot.key.ClientID, err)
+			return snapshot, 0, nil
+		}
+		return cachedKey{}, 0, fmt.Errorf("lookup api key: %w", err)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	limit, burst := a.quota(key)
+	cached, ok = a.keys[keyHash]
+	if !ok {
+		cached = &cachedKey{limiter: rate.NewLimiter(limit, burst)}
+		a.keys[keyHash] = cached
+	} else if cached.limiter.Limit() != limit || cached.limiter.Burst() != burst {
+		// Квота изменилась в хранилище, накопленное состояние ограничителя сохраняется
+		cached.limiter.SetLimitAt(a.now(), limit)
+		cached.limiter.SetBurstAt(a.now(), burst)
+	}
+	cached.key = key
+	cached.expires = a.now().Add(a.cfg.CacheTTL)
+	return *cached, 0, nil
+}
+
+// rememberInvalid расходует лимит неудачных проверок и запоминает неизвестный ключ
+func (a *Authenticator) rememberInvalid(keyHash string) {
+	now := a.now()
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	delete(a.keys, keyHash)
+	if a.failures != nil {
+		a.failures.AllowN(now, 1)
+	}
+	if a.cfg.NegativeCacheTTL <= 0 {
+		return
+	}
+	if len(a.invalid) >= maxInvalidKeys {
+		for hash, expires := range a.invalid {
+			if !now.Before(expires) {
+				delete(a.invalid, hash)
+			}
+		}
+	}
+	// Переполненный кэш не растет, незнакомые ключи и так ограничены лимитом неудачных проверок
+	if len(a.invalid) < maxInvalidKeys {
+		a.invalid[keyHash] = now.Add(a.cfg.NegativeCacheTTL)
+	}
+}
+
+// SetQuota меняет квоту ключей без собственных значений. Ограничители ключей из кэша
+// получают новую квоту при следующем запросе, накопленное состояние сохраняется
+func (a *Authenticator) SetQuota(limit float64, burst int) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.cfg.RateLimit, a.cfg.Burst = limit, burst
+	for _, cached := range a.keys {
+		cached.expires = time.Time{}
+	}
+}
+
+// quota вызывается под a.mu
+func (a *Authenticator) quota(key entity.APIKey) (rate.Limit, int) {
+	limit, burst := key.RateLimit, key.Burst
+	if limit == 0 {
+		limit = a.cfg.RateLimit
+	}
+	if burst == 0 {
+		burst = a.cfg.Burst
+	}
+	if limit <= 0 {
+		return rate.Inf, burst
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return rate.Limit(limit), burst
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingStore struct {
+	StaticStore
+	calls int
+	err   error
+}
+
+func (s *countingStore) APIKeyByHash(ctx context.Context, keyHash string) (entity.APIKey, error) {
+	s.calls++
+	if s.err != nil {
+		return entity.APIKey{}, s.err
+	}
+	return s.StaticStore.APIKeyByHash(ctx, keyHash)
+}
+
+func newTestAuthenticator(store KeyStore, cfg Config) (*Authenticator, *time.Time) {
+	now := time.Unix(1700000000, 0)
+	a := NewAuthenticator(store, cfg)
+	a.now = func() time.Time { return now }
+	return a, &now
+}
+
+func TestAuthenticate(t *testing.T) {
+	store := NewStaticStore(map[string]entity.APIKey{
+		"secret": {ClientID: "billing"},
+	})
+	a, _ := newTestAuthenticator(store, Config{CacheTTL: time.Minute})
+
+	key, _, err := a.Authenticate(context.Background(), "secret")
+	require.NoError(t, err)
+	require.Equal(t, "billing", key.ClientID)
+
+	_, _, err = a.Authenticate(context.Background(), "")
+	require.ErrorIs(t, err, ErrMissingKey)
+
+	_, _, err = a.Authenticate(context.Background(), "guess")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestAuthenticate_Quota(t *testing.T) {
+	store := NewStaticStore(map[string]entity.APIKey{
+		"default": {ClientID: "default"},
+		"own":     {ClientID: "own", RateLimit: 1, Burst: 1},
+	})
+	a, now := newTestAuthenticator(store, Config{RateLimit: 2, Burst: 2, CacheTTL: time.Minute})
+
+	// Квота по умолчанию: два запроса сразу, третий через полсекунды
+	for i := 0; i < 2; i++ {
+		_, _, err := a.Authenticate(context.Background(), "default")
+		require.NoError(t, err)
+	}
+	_, delay, err := a.Authenticate(context.Background(), "default")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	require.Equal(t, 500*time.Millisecond, delay)
+
+	// Собственная квота ключа и независимость ключей друг от друга
+	_, _, err = a.Authenticate(context.Background(), "own")
+	require.NoError(t, err)
+	_, delay, err = a.Authenticate(context.Background(), "own")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+	require.Equal(t, time.Second, delay)
+
+	*now = now.Add(time.Second)
+	_, _, err = a.Authenticate(context.Background(), "own")
+	require.NoError(t, err)
+}
+
+func TestSetQuota(t *testing.T) {
+	store := NewStaticStore(map[string]entity.APIKey{"secret": {ClientID: "billing"}})
+	a, _ := newTestAuthenticator(store, Config{RateLimit: 1, Burst: 1, CacheTTL: time.Hour})
+
+	_, _, err := a.Authenticate(context.Background(), "secret")
+	require.NoError(t, err)
+	_, _, err = a.Authenticate(context.Background(), "secret")
+	require.ErrorIs(t, err, ErrQuotaExceeded)
+
+	// Новая квота применяется к ключу из кэша без ожидания CacheTTL
+	a.SetQuota(0, 1)
+	for i := 0; i < 10; i++ {
+		_, _, err = a.Authenticate(context.Background(), "secret")
+		require.NoError(t, err)
+	}
+}
+
+func TestAuthenticate_Unlimited(t *testing.T) {
+	store := NewStaticStore(map[string]entity.APIKey{"secret": {ClientID: "billing"}})
+	a, _ := newTestAuthenticator(store, Config{})
+
+	for i := 0; i < 100; i++ {
+		_, _, err := a.Authenticate(context.Background(), "secret")
+		require.NoError(t, err)
+	}
+}
+
+func TestAuthenticate_Cache(t *testing.T) {
+	store := &countingStore{StaticStore: NewStaticStore(map[string]entity.APIKey{
+		"secret": {ClientID: "billing"},
+	})}
+	a, now := newTestAuthenticator(store, Config{CacheTTL: time.Minute})
+
+	for i := 0; i < 3; i++ {
+		_, _, err := a.Authenticate(context.Background(), "secret")
+		require.NoError(t, err)
+	}
+	require.Equal(t, 1, store.calls)
+
+	// Недоступное хранилище не отключает уже известные ключи
+	*now = now.Add(2 * time.Minute)
+	store.err = errors.New("db is down")
+	key, _, err := a.Authenticate(context.Background(), "secret")
+	require.NoError(t, err)
+	require.Equal(t, "billing", key.ClientID)
+	require.Equal(t, 2, store.calls)
+
+	_, _, err = a.Authenticate(context.Background(), "other")
+	require.ErrorContains(t, err, "db is down")
+
+	// Отозванный ключ перестает действовать после обновления
+	store.err = nil
+	delete(store.StaticStore, HashKey("secret"))
+	_, _, err = a.Authenticate(context.Background(), "secret")
+	require.ErrorIs(t, err, ErrInvalidKey)
+}
+
+func TestAuthenticate_InvalidKeys(t *testing.T) {
+	store := &countingStore{StaticStore: NewStaticStore(map[string]entity.APIKey{
+		"secret": {ClientID: "billing"},
+	})}
+	a, now := newTestAuthenticator(store, Config{CacheTTL: time.Minute, NegativeCacheTTL: 30 * time.Second,
+		FailedLookupRate: 1, FailedLookupBurst: 2})
+
+	// Повтор неизвестного ключа отклоняется без обращения к хранилищу
+	for i := 0; i < 3; i++ {
+		_, _, err := a.Authenticate(context.Background(), "guess")
+		require.ErrorIs(t, err, ErrInvalidKey)
+	}
+	require.Equal(t, 1, store.calls)
+
+	// После исчерпания лимита незнакомые ключи не проверяются в хранилище
+	_, _, err := a.Authenticate(context.Background(), "guess-2")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	_, delay, err := a.Authenticate(context.Background(), "guess-3")
+	require.ErrorIs(t, err, ErrTooManyFailures)
+	require.Equal(t, time.Second, delay)
+	require.Equal(t, 2, store.calls)
+
+	// Ключи из кэша продолжают работать, а лимит восстанавливается со временем
+	*now = now.Add(time.Second)
+	_, _, err = a.Authenticate(context.Background(), "secret")
+	require.NoError(t, err)
+	_, _, err = a.Authenticate(context.Background(), "guess-3")
+	require.ErrorIs(t, err, ErrInvalidKey)
+
+	// Запись кэша неизвестных ключей истекает
+	*now = now.Add(time.Minute)
+	_, _, err = a.Authenticate(context.Background(), "guess")
+	require.ErrorIs(t, err, ErrInvalidKey)
+	require.Equal(t, 5, store.calls)
+}
@@ -0,0 +1,99 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"rates/internal/entity"
+	"rates/internal/repository"
+	"rates/internal/service"
+	"rates/internal/source"
+	"strings"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// ErrorDomain - домен ошибок сервиса в google.rpc.ErrorInfo
+const ErrorDomain = "rates"
+
+// errorMapping описывает, как ошибка сервиса передается клиенту.
+// retryDelay > 0 добавляет google.rpc.RetryInfo: клиент может повторить запрос не раньше чем через retryDelay
+type errorMapping struct {
+	target     error
+	code       codes.Code
+	reason     string
+	retryDelay time.Duration
+}
+
+// errorMappings проверяются по порядку, первая подходящая ошибка определяет код.
+// Ошибки отмены и сроков идут первыми, так как они оборачиваются источниками
+var errorMappings = []errorMapping{
+	{context.Canceled, codes.Canceled, "CANCELED", 0},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, "DEADLINE_EXCEEDED", time.Second},
+	{service.ErrUnknownMarket, codes.InvalidArgument, "UNKNOWN_MARKET", 0},
+	{service.ErrInvalidArgument, codes.InvalidArgument, "INVALID_ARGUMENT", 0},
+	{repository.ErrNotFound, codes.NotFound, "NOT_FOUND", 0},
+	{entity.ErrRateLimited, codes.ResourceExhausted, "UPSTREAM_RATE_LIMITED", 5 * time.Second},
+	{service.ErrSlowConsumer, codes.ResourceExhausted, "SLOW_CONSUMER", time.Second},
+	{service.ErrShuttingDown, codes.Unavailable, "SHUTTING_DOWN", time.Second},
+	{entity.ErrUpstreamUnavailable, codes.Unavailable, "UPSTREAM_UNAVAILABLE", time.Second},
+	{entity.ErrStaleQuote, codes.Unavailable, "STALE_QUOTE", time.Second},
+	{entity.ErrEmptyBook, codes.Unavailable, "EMPTY_BOOK", time.Second},
+	{entity.ErrCrossedBook, codes.Unavailable, "CROSSED_BOOK", time.Second},
+	{service.ErrNoConsensus, codes.Unavailable, "NO_CONSENSUS", time.Second},
+	{source.ErrAllSourcesFailed, codes.Unavailable, "ALL_SOURCES_FAILED", time.Second},
+	{entity.ErrMalformedPayload, codes.Internal, "MALFORMED_UPSTREAM_PAYLOAD", 0},
+}
+
+// toStatusError переводит ошибку сервиса в gRPC статус с google.rpc.ErrorInfo и RetryInfo.
+// Неизвестные ошибки передаются как Internal без текста ошибки, готовые gRPC статусы не изменяются
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	mapping := errorMapping{code: codes.Internal, reason: "INTERNAL"}
+	for _, m := range errorMappings {
+		if errors.Is(err, m.target) {
+			mapping = m
+			break
+		}
+	}
+
+	// Текст передается клиенту только для ошибок в его запросе. Остальные ошибки могут содержать
+	// подробности базы данных или источника, поэтому пишутся в журнал, а клиент получает общее описание
+	message := err.Error()
+	switch mapping.code {
+	case codes.InvalidArgument, codes.NotFound:
+	case codes.Internal:
+		log.Errorf("Internal error: %v", err)
+		message = "internal error"
+	default:
+		log.Warnf("Request failed with %s: %v", mapping.reason, err)
+		message = strings.ToLower(strings.ReplaceAll(mapping.reason, "_", " "))
+	}
+
+	st := status.New(mapping.code, message)
+	withDetails, detailsErr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason: mapping.reason,
+		Domain: ErrorDomain,
+	})
+	if detailsErr != nil {
+		log.Errorf("Failed to attach error details: %v", detailsErr)
+		return st.Err()
+	}
+	if mapping.retryDelay > 0 {
+		if withRetry, err := withDetails.WithDetails(&errdetails.RetryInfo{
+			RetryDelay: durationpb.New(mapping.retryDelay),
+		}); err == nil {
+			withDetails = withRetry
+		}
+	}
+	return withDetails.Err()
+}
@@ -0,0 +1,90 @@
+package controller_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"rates/internal/controller"
+	"rates/internal/entity"
+	pb "rates/internal/infrastructure/pb"
+	"rates/internal/service"
+	"rates/internal/source"
+
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestController_ErrorStatus(t *testing.T) {
+	cases := []struct {
+		err     error
+		code    codes.Code
+		reason  string
+		retry   bool
+		message string
+	}{
+		{fmt.Errorf("%w: \"eurrub\"", service.ErrUnknownMarket), codes.InvalidArgument, "UNKNOWN_MARKET", false, `unknown market: "eurrub"`},
+		{fmt.Errorf("%w: status 503", entity.ErrUpstreamUnavailable), codes.Unavailable, "UPSTREAM_UNAVAILABLE", true, "upstream unavailable"},
+		{fmt.Errorf("%w: status 429", entity.ErrRateLimited), codes.ResourceExhausted, "UPSTREAM_RATE_LIMITED", true, "upstream rate limited"},
+		{fmt.Errorf("get: %w", context.DeadlineExceeded), codes.DeadlineExceeded, "DEADLINE_EXCEEDED", true, "deadline exceeded"},
+		{entity.ErrCrossedBook, codes.Unavailable, "CROSSED_BOOK", true, "crossed book"},
+		{service.ErrShuttingDown, codes.Unavailable, "SHUTTING_DOWN", true, "shutting down"},
+		{entity.ErrMalformedPayload, codes.Internal, "MALFORMED_UPSTREAM_PAYLOAD", false, "internal error"},
+		// Ошибки всех источников резервного списка сводятся к первой подходящей причине
+		{fmt.Errorf("%w: %w", source.ErrAllSourcesFailed, errors.Join(
+			fmt.Errorf("a: %w", entity.ErrRateLimited),
+			fmt.Errorf("b: %w", entity.ErrUpstreamUnavailable),
+		)), codes.ResourceExhausted, "UPSTREAM_RATE_LIMITED", true, "upstream rate limited"},
+		{errors.New("db is down"), codes.Internal, "INTERNAL", false, "internal error"},
+	}
+
+	for _, tc := range cases {
+		mockService := new(MockServicer)
+		ctrl := controller.NewController(mockService)
+		mockService.On("GetRates", mock.Anything, "usdtrub").Return(entity.Depth{}, tc.err)
+
+		_, err := ctrl.GetRates(context.Background(), &pb.RatesRequest{Market: "usdtrub"})
+
+		st, ok := status.FromError(err)
+		require.True(t, ok, tc.reason)
+		require.Equal(t, tc.code, st.Code(), tc.reason)
+		// Текст внутренних ошибок и ошибок источников не передается клиенту
+		require.Equal(t, tc.message, st.Message(), tc.reason)
+
+		var (
+			info  *errdetails.ErrorInfo
+			retry *errdetails.RetryInfo
+		)
+		for _, detail := range st.Details() {
+			switch d := detail.(type) {
+			case *errdetails.ErrorInfo:
+				info = d
+			case *errdetails.RetryInfo:
+				retry = d
+			}
+		}
+		require.NotNil(t, info, tc.reason)
+		require.Equal(t, tc.reason, info.GetReason())
+		require.Equal(t, controller.ErrorDomain, info.GetDomain())
+		if tc.retry {
+			require.NotNil(t, retry, tc.reason)
+			require.Positive(t, retry.GetRetryDelay().AsDuration(), tc.reason)
+		} else {
+			require.Nil(t, retry, tc.reason)
+		}
+	}
+}
+
+func TestController_ErrorStatus_Passthrough(t *testing.T) {
+	mockService := new(MockServicer)
+	ctrl := controller.NewController(mockService)
+	mockService.On("GetOrderBook", mock.Anything, "", 0).
+		Return(entity.OrderBook{}, status.Error(codes.PermissionDenied, "denied"))
+
+	_, err := ctrl.GetOrderBook(context.Background(), &pb.OrderBookRequest{})
+	require.Equal(t, codes.PermissionDenied, status.Code(err))
+}
@@ -3,7 +3,6 @@ package controller
 import (
 	"context"
 	"rates/internal/entity"
-	"rates/internal/infrastructure/metrics"
 	pb "rates/internal/infrastructure/pb"
 	"rates/pkg/logger"
 )
@@ -13,7 +12,13 @@ var (
 )
 
 type Servicer interface {
-	GetRates(ctx context.Context) (entity.Depth, error)
+	GetRates(ctx context.Context, market string) (entity.Depth, error)
+	GetOrderBook(ctx context.Context, market string, limit int) (entity.OrderBook, error)
+	GetHistory(ctx context.Context, query entity.HistoryQuery) (entity.HistoryPage, error)
+	GetCandles(ctx context.Context, query entity.CandleQuery) (entity.CandleSeries, error)
+	SubscribeRates(ctx context.Context, market string, send func(entity.Depth) error) error
+	QuoteConversion(ctx context.Context, query entity.ConversionQuery) (entity.Conversion, error)
+	GetAggregatedRates(ctx context.Context, query entity.AggregateQuery) (entity.AggregatedRate, error)
 }
 
 type Controller struct {
@@ -25,41 +30,223 @@ func NewController(service Servicer) *Controller {
 	return &Controller{service: service}
 }
 
-func (c Controller) GetRates(ctx context.Context, _ *pb.RatesRequest) (*pb.RatesResponse, error) {
-	log.Infof("Received GetRates request")
+func (c Controller) GetRates(ctx context.Context, req *pb.RatesRequest) (*pb.RatesResponse, error) {
+	log.Infof("Received GetRates request for market %q", req.GetMarket())
 
-	// метрика Prometheus общее количество запросов
-	metrics.CountRequestToService()
+	orders, err := c.service.GetRates(ctx, req.GetMarket())
+	if err != nil {
+		return &pb.RatesResponse{}, toStatusError(err)
+	}
+
+	depReq := toRatesResponse(orders)
+
+	log.Infof("Returning rates response for %s with Ask Price: %s, Bid Price: %s",
+		orders.Market, orders.Asks.Price, orders.Bids.Price)
+
+	return depReq, nil
+}
 
-	orders, err := c.service.GetRates(ctx)
+func (c Controller) GetOrderBook(ctx context.Context, req *pb.OrderBookRequest) (*pb.OrderBookResponse, error) {
+	log.Infof("Received GetOrderBook request for market %q with limit %d", req.GetMarket(), req.GetLimit())
+
+	book, err := c.service.GetOrderBook(ctx, req.GetMarket(), int(req.GetLimit()))
 	if err != nil {
-		return &pb.RatesResponse{}, err
+		return &pb.OrderBookResponse{}, toStatusError(err)
 	}
-	// Метрика Prometheus количества успешных ответов
-	metrics.CountSuccessRequestToService()
 
-	asResp := &pb.Order{
-		Price:  orders.Asks.Price,
-		Volume: orders.Asks.Volume,
-		Amount: orders.Asks.Amount,
-		Factor: orders.Asks.Factor,
-		Type:   orders.Asks.Type,
+	resp := &pb.OrderBookResponse{
+		Market:    book.Market,
+		Asks:      make([]*pb.Order, 0, len(book.Asks)),
+		Bids:      make([]*pb.Order, 0, len(book.Bids)),
+		Timestamp: book.Timestamp,
 	}
-	bitResp := &pb.Order{
-		Price:  orders.Bids.Price,
-		Volume: orders.Bids.Volume,
-		Amount: orders.Bids.Amount,
-		Factor: orders.Bids.Factor,
-		Type:   orders.Bids.Type,
+	for _, order := range book.Asks {
+		resp.Asks = append(resp.Asks, toPbOrder(order))
 	}
-	depReq := &pb.RatesResponse{
-		Ask:       asResp,
-		Bid:       bitResp,
-		Timestamp: orders.Timestamp,
+	for _, order := range book.Bids {
+		resp.Bids = append(resp.Bids, toPbOrder(order))
 	}
 
-	log.Infof("Returning rates response with Ask Price: %s, Bid Price: %s",
-		orders.Asks.Price, orders.Bids.Price)
+	log.Infof("Returning order book for %s with %d asks and %d bids", book.Market, len(resp.Asks), len(resp.Bids))
 
-	return depReq, nil
+	return resp, nil
+}
+
+func (c Controller) GetHistory(ctx context.Context, req *pb.HistoryRequest) (*pb.HistoryResponse, error) {
+	log.Infof("Received GetHistory request for market %q side %q from %d to %d",
+		req.GetMarket(), req.GetSide(), req.GetFrom(), req.GetTo())
+
+	page, err := c.service.GetHistory(ctx, entity.HistoryQuery{
+		Market:    req.GetMarket(),
+		Side:      req.GetSide(),
+		From:      req.GetFrom(),
+		To:        req.GetTo(),
+		PageSize:  int(req.GetPageSize()),
+		PageToken: req.GetPageToken(),
+	})
+	if err != nil {
+		return &pb.HistoryResponse{}, toStatusError(err)
+	}
+
+	resp := &pb.HistoryResponse{
+		Records:       make([]*pb.HistoryRecord, 0, len(page.Records)),
+		NextPageToken: page.NextPageToken,
+	}
+	for _, rec := range page.Records {
+		resp.Records = append(resp.Records, &pb.HistoryRecord{
+			Market:    rec.Market,
+			Side:      rec.Side,
+			Order:     toPbOrder(rec.Order),
+			Timestamp: rec.Timestamp,
+			Source:    rec.Source,
+		})
+	}
+
+	log.Infof("Returning %d history records", len(resp.Records))
+
+	return resp, nil
+}
+
+func (c Controller) GetCandles(ctx context.Context, req *pb.CandlesRequest) (*pb.CandlesResponse, error) {
+	log.Infof("Received GetCandles request for market %q side %q interval %q",
+		req.GetMarket(), req.GetSide(), req.GetInterval())
+
+	series, err := c.service.GetCandles(ctx, entity.CandleQuery{
+		Market:   req.GetMarket(),
+		Side:     req.GetSide(),
+		Interval: req.GetInterval(),
+		From:     req.GetFrom(),
+		To:       req.GetTo(),
+	})
+	if err != nil {
+		return &pb.CandlesResponse{}, toStatusError(err)
+	}
+
+	resp := &pb.CandlesResponse{
+		Market:   series.Market,
+		Side:     series.Side,
+		Interval: series.Interval,
+		Candles:  make([]*pb.Candle, 0, len(series.Candles)),
+	}
+	for _, candle := range series.Candles {
+		resp.Candles = append(resp.Candles, &pb.Candle{
+			Timestamp: candle.Timestamp,
+			Open:      candle.Open.String(),
+			High:      candle.High.String(),
+			Low:       candle.Low.String(),
+			Close:     candle.Close.String(),
+			Volume:    candle.Volume.String(),
+			Count:     candle.Count,
+		})
+	}
+
+	log.Infof("Returning %d candles", len(resp.Candles))
+
+	return resp, nil
+}
+
+func (c Controller) QuoteConversion(ctx context.Context, req *pb.ConversionRequest) (*pb.ConversionResponse, error) {
+	log.Infof("Received QuoteConversion request for market %q side %q amount %q",
+		req.GetMarket(), req.GetSide(), req.GetAmount())
+
+	conv, err := c.service.QuoteConversion(ctx, entity.ConversionQuery{
+		Market: req.GetMarket(),
+		Side:   req.GetSide(),
+		Amount: req.GetAmount(),
+	})
+	if err != nil {
+		return &pb.ConversionResponse{}, toStatusError(err)
+	}
+
+	log.Infof("Returning conversion quote for %s: average price %s, sufficient %t",
+		conv.Market, conv.AveragePrice, conv.Sufficient)
+
+	return &pb.ConversionResponse{
+		Market:       conv.Market,
+		Side:         conv.Side,
+		Timestamp:    conv.Timestamp,
+		Amount:       conv.Amount.String(),
+		Filled:       conv.Filled.String(),
+		AveragePrice: conv.AveragePrice.String(),
+		TotalCost:    conv.TotalCost.String(),
+		BestPrice:    conv.BestPrice.String(),
+		Slippage:     conv.Slippage.String(),
+		SlippageBps:  conv.SlippageBps.String(),
+		Levels:       int32(conv.Levels),
+		Sufficient:   conv.Sufficient,
+	}, nil
+}
+
+func (c Controller) GetAggregatedRates(ctx context.Context, req *pb.AggregatedRatesRequest) (*pb.AggregatedRatesResponse, error) {
+	log.Infof("Received GetAggregatedRates request for market %q method %q", req.GetMarket(), req.GetMethod())
+
+	rate, err := c.service.GetAggregatedRates(ctx, entity.AggregateQuery{
+		Market: req.GetMarket(),
+		Method: req.GetMethod(),
+	})
+	if err != nil {
+		return &pb.AggregatedRatesResponse{}, toStatusError(err)
+	}
+
+	resp := &pb.AggregatedRatesResponse{
+		Market:    rate.Market,
+		Method:    rate.Method,
+		Timestamp: rate.Timestamp,
+		Ask:       rate.Ask.String(),
+		Bid:       rate.Bid.String(),
+		Mid:       rate.Mid.String(),
+		Quotes:    make([]*pb.SourceQuote, 0, len(rate.Quotes)),
+	}
+	for _, quote := range rate.Quotes {
+		resp.Quotes = append(resp.Quotes, &pb.SourceQuote{
+			Source:    quote.Source,
+			Status:    quote.Status,
+			Error:     quote.Error,
+			Timestamp: quote.Timestamp,
+			Ask:       toPbOrder(quote.Asks),
+			Bid:       toPbOrder(quote.Bids),
+			Mid:       quote.Mid.String(),
+		})
+	}
+
+	log.Infof("Returning aggregated rate for %s from %d sources: mid %s", rate.Market, len(resp.Quotes), rate.Mid)
+
+	return resp, nil
+}
+
+func (c Controller) SubscribeRates(req *pb.RatesRequest, stream pb.GetRateser_SubscribeRatesServer) error {
+	log.Infof("Received SubscribeRates request for market %q", req.GetMarket())
+
+	err := c.service.SubscribeRates(stream.Context(), req.GetMarket(), func(dept entity.Depth) error {
+		return stream.Send(toRatesResponse(dept))
+	})
+	// Отключение клиента - штатное завершение подписки
+	if err != nil && stream.Context().Err() == nil {
+		log.Infof("Subscription for market %q closed: %v", req.GetMarket(), err)
+		return toStatusError(err)
+	}
+	return nil
+}
+
+func toRatesResponse(dept entity.Depth) *pb.RatesResponse {
+	return &pb.RatesResponse{
+		Ask:       toPbOrder(dept.Asks),
+		Bid:       toPbOrder(dept.Bids),
+		Timestamp: dept.Timestamp,
+		Market:    dept.Market,
+		Mid:       dept.Mid.String(),
+		Spread:    dept.Spread.String(),
+		SpreadBps: dept.SpreadBps.String(),
+		Source:    dept.Source,
+	}
+}
+
+func toPbOrder(order entity.Order) *pb.Order {
+	return &pb.Order{
+		Price:  order.Price.String(),
+		Volume: order.Volume.String(),
+		Amount: order.Amount.String(),
+		Factor: order.Factor,
+		Type:   order.Type,
+	}
 }
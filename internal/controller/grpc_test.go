@@ -18,11 +18,41 @@ type MockServicer struct {
 	mock.Mock
 }
 
-func (m *MockServicer) GetRates(ctx context.Context) (entity.Depth, error) {
-	args := m.Called(ctx)
+func (m *MockServicer) GetRates(ctx context.Context, market string) (entity.Depth, error) {
+	args := m.Called(ctx, market)
 	return args.Get(0).(entity.Depth), args.Error(1)
 }
 
+func (m *MockServicer) GetOrderBook(ctx context.Context, market string, limit int) (entity.OrderBook, error) {
+	args := m.Called(ctx, market, limit)
+	return args.Get(0).(entity.OrderBook), args.Error(1)
+}
+
+func (m *MockServicer) GetHistory(ctx context.Context, query entity.HistoryQuery) (entity.HistoryPage, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(entity.HistoryPage), args.Error(1)
+}
+
+func (m *MockServicer) GetCandles(ctx context.Context, query entity.CandleQuery) (entity.CandleSeries, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(entity.CandleSeries), args.Error(1)
+}
+
+func (m *MockServicer) SubscribeRates(ctx context.Context, market string, send func(entity.Depth) error) error {
+	args := m.Called(ctx, market, send)
+	return args.Error(0)
+}
+
+func (m *MockServicer) QuoteConversion(ctx context.Context, query entity.ConversionQuery) (entity.Conversion, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(entity.Conversion), args.Error(1)
+}
+
+func (m *MockServicer) GetAggregatedRates(ctx context.Context, query entity.AggregateQuery) (entity.AggregatedRate, error) {
+	args := m.Called(ctx, query)
+	return args.Get(0).(entity.AggregatedRate), args.Error(1)
+}
+
 func TestController_GetRates(t *testing.T) {
 	// Создаем mock сервиса
 	mockService := new(MockServicer)
@@ -35,28 +65,33 @@ func TestController_GetRates(t *testing.T) {
 
 	// Подготовленные данные
 	expectedDepth := entity.Depth{
+		Market: "btcrub",
 		Asks: entity.Order{
-			Price:  "100",
-			Volume: "1",
-			Amount: "100",
+			Price:  entity.MustDecimal("100"),
+			Volume: entity.MustDecimal("1"),
+			Amount: entity.MustDecimal("100"),
 			Factor: "2",
 			Type:   "ask",
 		},
 		Bids: entity.Order{
-			Price:  "90",
-			Volume: "1",
-			Amount: "90",
+			Price:  entity.MustDecimal("90"),
+			Volume: entity.MustDecimal("1"),
+			Amount: entity.MustDecimal("90"),
 			Factor: "2",
 			Type:   "bid",
 		},
 		Timestamp: 1234567890,
+		Mid:       entity.MustDecimal("95"),
+		Spread:    entity.MustDecimal("10"),
+		SpreadBps: entity.MustDecimal("1052.63"),
+		Source:    "garantex",
 	}
 
 	// Настройка мока для успешного вызова
-	mockService.On("GetRates", ctx).Return(expectedDepth, nil)
+	mockService.On("GetRates", ctx, "btcrub").Return(expectedDepth, nil)
 
 	// Вызов тестируемой функции
-	req := &pb.RatesRequest{}
+	req := &pb.RatesRequest{Market: "btcrub"}
 	resp, err := ctrl.GetRates(ctx, req)
 
 	// Проверяем отсутствие ошибок
@@ -66,6 +101,10 @@ func TestController_GetRates(t *testing.T) {
 	require.Equal(t, "100", resp.Ask.Price)
 	require.Equal(t, "90", resp.Bid.Price)
 	require.Equal(t, int64(1234567890), resp.Timestamp)
+	require.Equal(t, "95", resp.Mid)
+	require.Equal(t, "10", resp.Spread)
+	require.Equal(t, "1052.63", resp.SpreadBps)
+	require.Equal(t, "garantex", resp.Source)
 
 	// Убедимся, что метод сервиса был вызван один раз
 	mockService.AssertExpectations(t)
@@ -82,7 +121,7 @@ func TestController_GetRates_Error(t *testing.T) {
 	ctx := context.Background()
 
 	// Настройка мока для вызова с ошибкой
-	mockService.On("GetRates", ctx).Return(entity.Depth{}, errors.New("service error"))
+	mockService.On("GetRates", ctx, "").Return(entity.Depth{}, errors.New("service error"))
 
 	// Вызов тестируемой функции
 	req := &pb.RatesRequest{}
@@ -95,3 +134,109 @@ func TestController_GetRates_Error(t *testing.T) {
 	// Убедимся, что метод сервиса был вызван один раз
 	mockService.AssertExpectations(t)
 }
+
+func TestController_GetOrderBook(t *testing.T) {
+	mockService := new(MockServicer)
+	ctrl := controller.NewController(mockService)
+	ctx := context.Background()
+
+	book := entity.OrderBook{
+		Market:    "usdtrub",
+		Timestamp: 1234567890,
+		Asks:      []entity.Order{{Price: entity.MustDecimal("100")}, {Price: entity.MustDecimal("101")}},
+		Bids:      []entity.Order{{Price: entity.MustDecimal("90")}},
+	}
+	mockService.On("GetOrderBook", ctx, "usdtrub", 2).Return(book, nil)
+
+	resp, err := ctrl.GetOrderBook(ctx, &pb.OrderBookRequest{Market: "usdtrub", Limit: 2})
+	require.NoError(t, err)
+
+	// Уровни стакана передаются в ответ в исходном порядке
+	require.Len(t, resp.Asks, 2)
+	require.Equal(t, "101", resp.Asks[1].Price)
+	require.Len(t, resp.Bids, 1)
+	require.Equal(t, "usdtrub", resp.Market)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_QuoteConversion(t *testing.T) {
+	mockService := new(MockServicer)
+	ctrl := controller.NewController(mockService)
+	ctx := context.Background()
+
+	conv := entity.Conversion{
+		Market:       "usdtrub",
+		Side:         "buy",
+		Amount:       entity.MustDecimal("3.5"),
+		Filled:       entity.MustDecimal("3.5"),
+		AveragePrice: entity.MustDecimal("101.28571429"),
+		TotalCost:    entity.MustDecimal("354.5"),
+		Levels:       3,
+		Sufficient:   true,
+	}
+	mockService.On("QuoteConversion", ctx, entity.ConversionQuery{Market: "usdtrub", Side: "buy", Amount: "3.5"}).
+		Return(conv, nil)
+
+	resp, err := ctrl.QuoteConversion(ctx, &pb.ConversionRequest{Market: "usdtrub", Side: "buy", Amount: "3.5"})
+	require.NoError(t, err)
+	require.Equal(t, "354.5", resp.TotalCost)
+	require.Equal(t, "101.28571429", resp.AveragePrice)
+	require.Equal(t, int32(3), resp.Levels)
+	require.True(t, resp.Sufficient)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_GetCandles(t *testing.T) {
+	mockService := new(MockServicer)
+	ctrl := controller.NewController(mockService)
+	ctx := context.Background()
+
+	series := entity.CandleSeries{Market: "btcrub", Side: "asks", Interval: "1h", Candles: []entity.Candle{
+		{Timestamp: 3600, Open: entity.MustDecimal("90"), High: entity.MustDecimal("92"),
+			Low: entity.MustDecimal("89"), Close: entity.MustDecimal("91"), Volume: entity.MustDecimal("7.5"), Count: 4},
+	}}
+	query := entity.CandleQuery{Market: "BTC-RUB", Side: "ask", Interval: "1h"}
+	mockService.On("GetCandles", ctx, query).Return(series, nil)
+
+	resp, err := ctrl.GetCandles(ctx, &pb.CandlesRequest{Market: "BTC-RUB", Side: "ask", Interval: "1h"})
+	require.NoError(t, err)
+	// В ответе рынок и сторона, по которым построены свечи, а не значения из запроса
+	require.Equal(t, "btcrub", resp.Market)
+	require.Equal(t, "asks", resp.Side)
+	require.Len(t, resp.Candles, 1)
+	require.Equal(t, "91", resp.Candles[0].Close)
+	require.Equal(t, "7.5", resp.Candles[0].Volume)
+	require.Equal(t, int64(4), resp.Candles[0].Count)
+
+	mockService.AssertExpectations(t)
+}
+
+func TestController_GetAggregatedRates(t *testing.T) {
+	mockService := new(MockServicer)
+	ctrl := controller.NewController(mockService)
+	ctx := context.Background()
+
+	rate := entity.AggregatedRate{
+		Market: "usdtrub",
+		Method: "median",
+		Ask:    entity.MustDecimal("102"),
+		Bid:    entity.MustDecimal("100"),
+		Mid:    entity.MustDecimal("101"),
+		Quotes: []entity.SourceQuote{
+			{Source: "garantex", Status: entity.QuoteAccepted, Asks: entity.Order{Price: entity.MustDecimal("102")}},
+			{Source: "backup", Status: entity.QuoteFailed, Error: "timeout"},
+		},
+	}
+	mockService.On("GetAggregatedRates", ctx, entity.AggregateQuery{Market: "usdtrub"}).Return(rate, nil)
+
+	resp, err := ctrl.GetAggregatedRates(ctx, &pb.AggregatedRatesRequest{Market: "usdtrub"})
+	require.NoError(t, err)
+	require.Equal(t, "101", resp.Mid)
+	require.Len(t, resp.Quotes, 2)
+	require.Equal(t, "102", resp.Quotes[0].Ask.Price)
+	require.Equal(t, "timeout", resp.Quotes[1].Error)
+
+	mockService.AssertExpectations(t)
+}
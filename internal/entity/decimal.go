@@ -0,0 +1,304 @@
+package entity
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// DecimalScale - количество знаков после запятой, как у колонок DECIMAL(18, 8)
+	DecimalScale = 8
+	// DecimalPrecision - общее количество значащих цифр
+	DecimalPrecision = 18
+
+	decimalFactor   = 100_000_000
+	maxDecimalUnits = 999_999_999_999_999_999
+)
+
+var (
+	ErrInvalidDecimal  = errors.New("invalid decimal")
+	ErrDecimalOverflow = errors.New("decimal overflow")
+	ErrDivisionByZero  = errors.New("decimal division by zero")
+)
+
+// Decimal - число с фиксированной точкой и DecimalScale знаками после запятой.
+// Хранится как целое количество 1e-8 долей, поэтому сложение и сравнение точные.
+// Лишние знаки округляются половиной от нуля (как numeric в Postgres),
+// значения за пределами DECIMAL(18, 8) считаются ошибкой.
+// Нулевое значение - это 0
+type Decimal struct {
+	units int64
+}
+
+// ParseDecimal разбирает десятичную строку вида "-123.45". Экспоненциальная запись не поддерживается
+func ParseDecimal(s string) (Decimal, error) {
+	str := strings.TrimSpace(s)
+	neg := false
+	if str != "" && (str[0] == '-' || str[0] == '+') {
+		neg = str[0] == '-'
+		str = str[1:]
+	}
+
+	intPart, fracPart, _ := strings.Cut(str, ".")
+	if intPart == "" && fracPart == "" || !isDigits(intPart) || !isDigits(fracPart) {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrInvalidDecimal, s)
+	}
+
+	// Округление до DecimalScale знаков половиной от нуля
+	roundUp := false
+	if len(fracPart) > DecimalScale {
+		roundUp = fracPart[DecimalScale] >= '5'
+		fracPart = fracPart[:DecimalScale]
+	}
+	fracPart += strings.Repeat("0", DecimalScale-len(fracPart))
+
+	intPart = strings.TrimLeft(intPart, "0")
+	if len(intPart) > DecimalPrecision-DecimalScale {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrDecimalOverflow, s)
+	}
+
+	var units int64
+	if intPart != "" {
+		i, _ := strconv.ParseInt(intPart, 10, 64)
+		units = i * decimalFactor
+	}
+	f, _ := strconv.ParseInt(fracPart, 10, 64)
+	units += f
+	if roundUp {
+		units++
+	}
+	if units > maxDecimalUnits {
+		return Decimal{}, fmt.Errorf("%w: %q", ErrDecimalOverflow, s)
+	}
+	if neg {
+		units = -units
+	}
+	return Decimal{units: units}, nil
+}
+
+// MustDecimal разбирает строку и паникует при ошибке. Предназначен для констант и тестов
+func MustDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// NewDecimalFromInt создает Decimal из целого числа
+func NewDecimalFromInt(i int64) Decimal {
+	return Decimal{units: i * decimalFactor}
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func (d Decimal) String() string {
+	units := d.units
+	sign := ""
+	if units < 0 {
+		sign, units = "-", -units
+	}
+
+	s := sign + strconv.FormatInt(units/decimalFactor, 10)
+	if frac := units % decimalFactor; frac != 0 {
+		s += "." + strings.TrimRight(fmt.Sprintf("%0*d", DecimalScale, frac), "0")
+	}
+	return s
+}
+
+// Float64 возвращает приближенное значение для метрик и логов. Не использовать в расчетах
+func (d Decimal) Float64() float64 {
+	return float64(d.units) / decimalFactor
+}
+
+func (d Decimal) Sign() int {
+	switch {
+	case d.units < 0:
+		return -1
+	case d.units > 0:
+		return 1
+	}
+	return 0
+}
+
+func (d Decimal) IsZero() bool {
+	return d.units == 0
+}
+
+// Cmp возвращает -1, 0 или 1, если d меньше, равно или больше other
+func (d Decimal) Cmp(other Decimal) int {
+	switch {
+	case d.units < other.units:
+		return -1
+	case d.units > other.units:
+		return 1
+	}
+	return 0
+}
+
+func (d Decimal) Add(other Decimal) Decimal {
+	return Decimal{units: d.units + other.units}
+}
+
+// AddChecked складывает с проверкой результата на переполнение
+func (d Decimal) AddChecked(other Decimal) (Decimal, error) {
+	return fromBig(new(big.Int).Add(big.NewInt(d.units), big.NewInt(other.units)))
+}
+
+func (d Decimal) Sub(other Decimal) Decimal {
+	return Decimal{units: d.units - other.units}
+}
+
+func (d Decimal) Neg() Decimal {
+	return Decimal{units: -d.units}
+}
+
+func (d Decimal) Abs() Decimal {
+	if d.units < 0 {
+		return d.Neg()
+	}
+	return d
+}
+
+// Mul умножает с округлением результата до DecimalScale знаков
+func (d Decimal) Mul(other Decimal) (Decimal, error) {
+	num := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(other.units))
+	return fromBig(divRound(num, big.NewInt(decimalFactor)))
+}
+
+// Quo делит с округлением результата до DecimalScale знаков
+func (d Decimal) Quo(other Decimal) (Decimal, error) {
+	if other.units == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	num := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(decimalFactor))
+	return fromBig(divRound(num, big.NewInt(other.units)))
+}
+
+// QuoRound делит с однократным округлением результата до places знаков
+func (d Decimal) QuoRound(other Decimal, places int) (Decimal, error) {
+	return d.MulQuoRound(NewDecimalFromInt(1), other, places)
+}
+
+// MulQuoRound вычисляет d * num / den с однократным округлением результата до places знаков.
+// Промежуточное произведение не ограничивается, переполнением считается только итоговое значение
+func (d Decimal) MulQuoRound(num, den Decimal, places int) (Decimal, error) {
+	if den.units == 0 {
+		return Decimal{}, ErrDivisionByZero
+	}
+	if places > DecimalScale {
+		places = DecimalScale
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := int64(1)
+	for i := places; i < DecimalScale; i++ {
+		step *= 10
+	}
+	// d*num/den в долях 1e-8, округленное до шага step: d * num / (den * step) * step
+	n := new(big.Int).Mul(big.NewInt(d.units), big.NewInt(num.units))
+	m := new(big.Int).Mul(big.NewInt(den.units), big.NewInt(step))
+	q := divRound(n, m)
+	return fromBig(q.Mul(q, big.NewInt(step)))
+}
+
+// Round округляет до places знаков после запятой половиной от нуля
+func (d Decimal) Round(places int) Decimal {
+	if places >= DecimalScale {
+		return d
+	}
+	if places < 0 {
+		places = 0
+	}
+	step := int64(1)
+	for i := places; i < DecimalScale; i++ {
+		step *= 10
+	}
+	q := divRound(big.NewInt(d.units), big.NewInt(step))
+	return Decimal{units: q.Int64() * step}
+}
+
+// divRound делит num на den с округлением половиной от нуля
+func divRound(num, den *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(num, den, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(den)) >= 0 {
+		if num.Sign()*den.Sign() < 0 {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
+
+func fromBig(units *big.Int) (Decimal, error) {
+	if units.CmpAbs(big.NewInt(maxDecimalUnits)) > 0 {
+		return Decimal{}, ErrDecimalOverflow
+	}
+	return Decimal{units: units.Int64()}, nil
+}
+
+// MarshalJSON кодирует число строкой, чтобы не терять точность в клиентах
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(d.String())), nil
+}
+
+// UnmarshalJSON принимает число как строкой, так и JSON числом
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		s = unquoted
+	}
+	parsed, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+// Value передает число в базу строкой, которую Postgres приводит к DECIMAL без потерь
+func (d Decimal) Value() (driver.Value, error) {
+	return d.String(), nil
+}
+
+func (d *Decimal) Scan(src interface{}) error {
+	var (
+		parsed Decimal
+		err    error
+	)
+	switch v := src.(type) {
+	case nil:
+		parsed = Decimal{}
+	case []byte:
+		parsed, err = ParseDecimal(string(v))
+	case string:
+		parsed, err = ParseDecimal(v)
+	case int64:
+		parsed = NewDecimalFromInt(v)
+	case float64:
+		parsed, err = ParseDecimal(strconv.FormatFloat(v, 'f', -1, 64))
+	default:
+		return fmt.Errorf("%w: cannot scan %T", ErrInvalidDecimal, src)
+	}
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
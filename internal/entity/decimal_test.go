@@ -0,0 +1,124 @@
+package entity
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDecimal(t *testing.T) {
+	cases := map[string]string{
+		"100":          "100",
+		"95.61000000":  "95.61",
+		"-0.5":         "-0.5",
+		"+7.":          "7",
+		".25":          "0.25",
+		"0.123456785":  "0.12345679",
+		"-0.123456785": "-0.12345679",
+		"0.123456784":  "0.12345678",
+		"0009.10":      "9.1",
+	}
+	for in, want := range cases {
+		d, err := ParseDecimal(in)
+		require.NoError(t, err, in)
+		require.Equal(t, want, d.String(), in)
+	}
+
+	for _, in := range []string{"", "-", ".", "abc", "1e5", "1.2.3", "12 3"} {
+		_, err := ParseDecimal(in)
+		require.ErrorIs(t, err, ErrInvalidDecimal, in)
+	}
+
+	// Больше 10 цифр целой части не помещается в DECIMAL(18, 8)
+	_, err := ParseDecimal("12345678901")
+	require.ErrorIs(t, err, ErrDecimalOverflow)
+	_, err = ParseDecimal("9999999999.999999999")
+	require.ErrorIs(t, err, ErrDecimalOverflow)
+}
+
+func TestDecimalArithmetic(t *testing.T) {
+	a, b := MustDecimal("95.5"), MustDecimal("95.1")
+
+	require.Equal(t, "190.6", a.Add(b).String())
+	require.Equal(t, "0.4", a.Sub(b).String())
+	require.Equal(t, 1, a.Cmp(b))
+	require.Equal(t, -1, b.Sub(a).Sign())
+
+	mul, err := a.Mul(MustDecimal("0.001"))
+	require.NoError(t, err)
+	require.Equal(t, "0.0955", mul.String())
+
+	// 2/3 округляется до 8 знаков
+	quo, err := NewDecimalFromInt(2).Quo(NewDecimalFromInt(3))
+	require.NoError(t, err)
+	require.Equal(t, "0.66666667", quo.String())
+
+	quo, err = NewDecimalFromInt(-2).Quo(NewDecimalFromInt(3))
+	require.NoError(t, err)
+	require.Equal(t, "-0.66666667", quo.String())
+
+	_, err = a.Quo(Decimal{})
+	require.ErrorIs(t, err, ErrDivisionByZero)
+
+	// Однократное округление: 1/200.0000002 = 0.0049999999..., а 0.00500000 округлился бы до 0.01
+	quo, err = NewDecimalFromInt(1).QuoRound(MustDecimal("200.0000002"), 2)
+	require.NoError(t, err)
+	require.Equal(t, "0", quo.String())
+	quo, err = NewDecimalFromInt(-2).QuoRound(NewDecimalFromInt(3), 2)
+	require.NoError(t, err)
+	require.Equal(t, "-0.67", quo.String())
+	_, err = a.QuoRound(Decimal{}, 2)
+	require.ErrorIs(t, err, ErrDivisionByZero)
+
+	_, err = MustDecimal("9999999999").Mul(NewDecimalFromInt(10))
+	require.ErrorIs(t, err, ErrDecimalOverflow)
+
+	// Произведение выходит за пределы Decimal, частное - нет
+	res, err := MustDecimal("9999999999").MulQuoRound(MustDecimal("20000"), MustDecimal("9999999999.5"), 2)
+	require.NoError(t, err)
+	require.Equal(t, "20000", res.String())
+	_, err = MustDecimal("9999999999").MulQuoRound(MustDecimal("20000"), NewDecimalFromInt(1), 2)
+	require.ErrorIs(t, err, ErrDecimalOverflow)
+	_, err = a.MulQuoRound(b, Decimal{}, 2)
+	require.ErrorIs(t, err, ErrDivisionByZero)
+
+	sum, err := a.AddChecked(b)
+	require.NoError(t, err)
+	require.Equal(t, "190.6", sum.String())
+	_, err = MustDecimal("9999999999").AddChecked(NewDecimalFromInt(1))
+	require.ErrorIs(t, err, ErrDecimalOverflow)
+
+	require.Equal(t, "41.88", MustDecimal("41.8751").Round(2).String())
+	require.Equal(t, "-41.88", MustDecimal("-41.875").Round(2).String())
+	require.Equal(t, "42", MustDecimal("41.5").Round(0).String())
+}
+
+func TestDecimalJSON(t *testing.T) {
+	var order Order
+	err := json.Unmarshal([]byte(`{"price":"95.61","volume":12.5,"amount":"1195.125"}`), &order)
+	require.NoError(t, err)
+	require.Equal(t, MustDecimal("95.61"), order.Price)
+	require.Equal(t, MustDecimal("12.5"), order.Volume)
+
+	data, err := json.Marshal(order.Price)
+	require.NoError(t, err)
+	require.Equal(t, `"95.61"`, string(data))
+
+	err = json.Unmarshal([]byte(`{"price":"<html>"}`), &order)
+	require.ErrorIs(t, err, ErrInvalidDecimal)
+}
+
+func TestDecimalSQL(t *testing.T) {
+	var d Decimal
+	require.NoError(t, d.Scan([]byte("100.50000000")))
+	require.Equal(t, "100.5", d.String())
+
+	v, err := d.Value()
+	require.NoError(t, err)
+	require.Equal(t, "100.5", v)
+
+	require.NoError(t, d.Scan(int64(3)))
+	require.Equal(t, NewDecimalFromInt(3), d)
+	require.Error(t, d.Scan(true))
+}
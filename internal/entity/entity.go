@@ -1,21 +1,236 @@
 package entity
 
+import "fmt"
+
 type Order struct {
-	Price  string `json:"price"`
-	Volume string `json:"volume"`
-	Amount string `json:"amount"`
-	Factor string `json:"factor"`
-	Type   string `json:"type"`
+	Price  Decimal `json:"price"`
+	Volume Decimal `json:"volume"`
+	Amount Decimal `json:"amount"`
+	Factor string  `json:"factor"`
+	Type   string  `json:"type"`
+}
+
+// Validate проверяет уровень стакана от источника: цена и объем должны быть положительными
+func (o Order) Validate() error {
+	if o.Price.Sign() <= 0 {
+		return fmt.Errorf("%w: non-positive price %s", ErrInvalidDecimal, o.Price)
+	}
+	if o.Volume.Sign() <= 0 {
+		return fmt.Errorf("%w: non-positive volume %s", ErrInvalidDecimal, o.Volume)
+	}
+	if o.Amount.Sign() < 0 {
+		return fmt.Errorf("%w: negative amount %s", ErrInvalidDecimal, o.Amount)
+	}
+	return nil
 }
 
 type Depth struct {
-	Timestamp int64 `json:"timestamp"`
-	Asks      Order `json:"asks"`
-	Bids      Order `json:"bids"`
+	Market    string `json:"market"`
+	Timestamp int64  `json:"timestamp"`
+	Asks      Order  `json:"asks"`
+	Bids      Order  `json:"bids"`
+	// Mid - середина между лучшими ask и bid
+	Mid Decimal `json:"mid"`
+	// Spread - разница между лучшими ask и bid
+	Spread Decimal `json:"spread"`
+	// SpreadBps - спред в базисных пунктах от Mid
+	SpreadBps Decimal `json:"spread_bps"`
+	// Source - источник, от которого получен снимок
+	Source string `json:"source"`
 }
 
 type DepthRequest struct {
 	Timestamp int64   `json:"timestamp"`
 	Asks      []Order `json:"asks"`
 	Bids      []Order `json:"bids"`
+	// Source - источник, ответивший на запрос. Заполняется источником, а не из ответа биржи
+	Source string `json:"-"`
+}
+
+// Validate проверяет все уровни стакана от источника
+func (d DepthRequest) Validate() error {
+	if d.Timestamp <= 0 {
+		return fmt.Errorf("%w: missing timestamp", ErrMalformedPayload)
+	}
+	for i, order := range d.Asks {
+		if err := order.Validate(); err != nil {
+			return fmt.Errorf("%w: asks[%d]: %w", ErrMalformedPayload, i, err)
+		}
+	}
+	for i, order := range d.Bids {
+		if err := order.Validate(); err != nil {
+			return fmt.Errorf("%w: bids[%d]: %w", ErrMalformedPayload, i, err)
+		}
+	}
+	return nil
+}
+
+// CheckBook проверяет, что по стакану можно получить котировку: обе стороны не пустые и ask >= bid
+func (d DepthRequest) CheckBook() error {
+	if len(d.Asks) == 0 || len(d.Bids) == 0 {
+		return fmt.Errorf("%w: %d asks, %d bids", ErrEmptyBook, len(d.Asks), len(d.Bids))
+	}
+	if d.Asks[0].Price.Cmp(d.Bids[0].Price) < 0 {
+		return fmt.Errorf("%w: best ask %s below best bid %s", ErrCrossedBook, d.Asks[0].Price, d.Bids[0].Price)
+	}
+	return nil
+}
+
+// OrderBook - снимок стакана по рынку с несколькими уровнями на сторону
+type OrderBook struct {
+	Market    string  `json:"market"`
+	Timestamp int64   `json:"timestamp"`
+	Asks      []Order `json:"asks"`
+	Bids      []Order `json:"bids"`
+}
+
+// HistoryQuery - параметры запроса истории котировок. Время задается в unix секундах
+type HistoryQuery struct {
+	Market    string
+	Side      string
+	From      int64
+	To        int64
+	PageSize  int
+	PageToken string
+}
+
+// HistoryFilter - параметры выборки сохраненных котировок.
+// Выборка упорядочена по (Timestamp, ID) и начинается после AfterTimestamp/AfterID
+type HistoryFilter struct {
+	Market         string
+	Side           string
+	From           int64
+	To             int64
+	AfterTimestamp int64
+	AfterID        int64
+	Limit          int
+}
+
+// HistoryRecord - сохраненная котировка одной стороны стакана
+type HistoryRecord struct {
+	ID        int64  `json:"id"`
+	Market    string `json:"market"`
+	Side      string `json:"side"`
+	Timestamp int64  `json:"timestamp"`
+	Order     Order  `json:"order"`
+	Source    string `json:"source"`
+}
+
+// HistoryPage - страница истории котировок и токен следующей страницы
+type HistoryPage struct {
+	Records       []HistoryRecord `json:"records"`
+	NextPageToken string          `json:"next_page_token"`
+}
+
+// CandleQuery - параметры запроса свечей. Interval задается строкой: 1m, 5m, 1h, 1d
+type CandleQuery struct {
+	Market   string
+	Side     string
+	Interval string
+	From     int64
+	To       int64
+}
+
+// CandleFilter - параметры агрегации свечей в репозитории. Interval в секундах
+type CandleFilter struct {
+	Market   string
+	Side     string
+	Interval int64
+	From     int64
+	To       int64
+}
+
+// Candle - OHLC свеча по сохраненным котировкам. Timestamp - начало интервала
+type Candle struct {
+	Timestamp int64   `json:"timestamp"`
+	Open      Decimal `json:"open"`
+	High      Decimal `json:"high"`
+	Low       Decimal `json:"low"`
+	Close     Decimal `json:"close"`
+	// Volume - сумма объемов лучшего уровня по котировкам интервала (top-of-book), а не объем торгов
+	Volume Decimal `json:"volume"`
+	// Count - количество котировок в интервале
+	Count int64 `json:"count"`
+}
+
+// CandleSeries - свечи с нормализованными рынком, стороной и интервалом, по которым они построены
+type CandleSeries struct {
+	Market   string   `json:"market"`
+	Side     string   `json:"side"`
+	Interval string   `json:"interval"`
+	Candles  []Candle `json:"candles"`
+}
+
+// ConversionQuery - параметры расчета конвертации по стакану.
+// Side - направление сделки: buy проходит по asks, sell по bids. Amount - объем в базовой валюте
+type ConversionQuery struct {
+	Market string
+	Side   string
+	Amount string
+}
+
+// Conversion - результат прохода по стакану на заданный объем
+type Conversion struct {
+	Market    string `json:"market"`
+	Side      string `json:"side"`
+	Timestamp int64  `json:"timestamp"`
+	// Amount - запрошенный объем, Filled - объем, который удалось набрать в стакане
+	Amount Decimal `json:"amount"`
+	Filled Decimal `json:"filled"`
+	// AveragePrice - средневзвешенная по объему цена исполнения
+	AveragePrice Decimal `json:"average_price"`
+	// TotalCost - стоимость набранного объема в котируемой валюте
+	TotalCost Decimal `json:"total_cost"`
+	// BestPrice - лучшая цена стороны стакана
+	BestPrice Decimal `json:"best_price"`
+	// Slippage - отклонение средней цены от лучшей, SlippageBps - то же в базисных пунктах
+	Slippage    Decimal `json:"slippage"`
+	SlippageBps Decimal `json:"slippage_bps"`
+	// Levels - количество затронутых уровней стакана
+	Levels int `json:"levels"`
+	// Sufficient - ликвидности стакана хватило на весь объем
+	Sufficient bool `json:"sufficient"`
+}
+
+// Статусы котировки источника в сводном курсе
+const (
+	QuoteAccepted = "ok"
+	QuoteStale    = "stale"
+	QuoteOutlier  = "outlier"
+	QuoteFailed   = "error"
+)
+
+// AggregateQuery - параметры запроса сводного курса. Method: median или weighted
+type AggregateQuery struct {
+	Market string
+	Method string
+}
+
+// SourceQuote - лучшие цены одного источника и признак их участия в сводном курсе
+type SourceQuote struct {
+	Source    string  `json:"source"`
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	Timestamp int64   `json:"timestamp"`
+	Asks      Order   `json:"asks"`
+	Bids      Order   `json:"bids"`
+	Mid       Decimal `json:"mid"`
+}
+
+// AggregatedRate - сводный курс по нескольким источникам
+type AggregatedRate struct {
+	Market    string        `json:"market"`
+	Method    string        `json:"method"`
+	Timestamp int64         `json:"timestamp"`
+	Ask       Decimal       `json:"ask"`
+	Bid       Decimal       `json:"bid"`
+	Mid       Decimal       `json:"mid"`
+	Quotes    []SourceQuote `json:"quotes"`
+}
+
+// APIKey - клиент API и его квота. Нулевые RateLimit и Burst означают значения по умолчанию
+type APIKey struct {
+	ClientID  string
+	RateLimit float64
+	Burst     int
 }
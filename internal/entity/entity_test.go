@@ -0,0 +1,51 @@
+package entity
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrderValidate(t *testing.T) {
+	order := Order{Price: MustDecimal("100"), Volume: MustDecimal("1"), Amount: MustDecimal("100")}
+	require.NoError(t, order.Validate())
+
+	order.Price = Decimal{}
+	require.Error(t, order.Validate())
+}
+
+func TestDepthRequestCheckBook(t *testing.T) {
+	level := func(price string) Order {
+		return Order{Price: MustDecimal(price), Volume: MustDecimal("1")}
+	}
+
+	depth := DepthRequest{Timestamp: 1, Asks: []Order{level("100")}, Bids: []Order{level("90")}}
+	require.NoError(t, depth.Validate())
+	require.NoError(t, depth.CheckBook())
+
+	// Сомкнутый стакан допустим, перекрестный нет
+	depth.Bids = []Order{level("100")}
+	require.NoError(t, depth.CheckBook())
+	depth.Bids = []Order{level("101")}
+	require.ErrorIs(t, depth.CheckBook(), ErrCrossedBook)
+
+	depth.Bids = nil
+	require.ErrorIs(t, depth.CheckBook(), ErrEmptyBook)
+
+	depth.Timestamp = 0
+	require.ErrorIs(t, depth.Validate(), ErrMalformedPayload)
+
+	depth = DepthRequest{Timestamp: 1, Asks: []Order{{Price: MustDecimal("100")}}}
+	err := depth.Validate()
+	require.ErrorIs(t, err, ErrMalformedPayload)
+	require.ErrorIs(t, err, ErrInvalidDecimal)
+}
+
+func TestNormalizeMarkets(t *testing.T) {
+	require.Equal(t, "btcrub", NormalizeMarket(" BTC-RUB "))
+	require.Equal(t, "usdtrub", NormalizeMarket("usdt_rub"))
+	require.Equal(t, "ethusdt", NormalizeMarket("ETH/USDT"))
+
+	// Повторы после нормализации и пустые элементы отбрасываются, порядок сохраняется
+	require.Equal(t, []string{"btcrub", "usdtrub"}, NormalizeMarkets([]string{"BTC-RUB", " ", "usdtrub", "btcrub"}))
+}
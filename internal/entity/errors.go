@@ -0,0 +1,20 @@
+package entity
+
+import "errors"
+
+// Ошибки получения котировок от источника. Источники и сервис оборачивают их,
+// чтобы вызывающий код мог различать причины через errors.Is
+var (
+	// ErrUpstreamUnavailable - источник не ответил: сетевая ошибка, таймаут или статус 5xx
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	// ErrRateLimited - источник ограничил частоту запросов (статус 429)
+	ErrRateLimited = errors.New("upstream rate limited")
+	// ErrMalformedPayload - ответ источника не удалось разобрать или он содержит некорректные значения
+	ErrMalformedPayload = errors.New("malformed upstream payload")
+	// ErrEmptyBook - в стакане нет ни одного уровня на одной из сторон
+	ErrEmptyBook = errors.New("empty order book")
+	// ErrCrossedBook - лучший ask ниже лучшего bid
+	ErrCrossedBook = errors.New("crossed order book")
+	// ErrStaleQuote - снимок стакана старше допустимого возраста
+	ErrStaleQuote = errors.New("stale quote")
+)
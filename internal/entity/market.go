@@ -0,0 +1,22 @@
+package entity
+
+import "strings"
+
+// NormalizeMarket приводит название рынка к формату биржи: "BTC-RUB" -> "btcrub"
+func NormalizeMarket(market string) string {
+	market = strings.ToLower(strings.TrimSpace(market))
+	return strings.NewReplacer("-", "", "_", "", "/", "").Replace(market)
+}
+
+// NormalizeMarkets приводит рынки к формату биржи, пропуская пустые названия и повторы. Порядок сохраняется
+func NormalizeMarkets(markets []string) []string {
+	normalized := make([]string, 0, len(markets))
+	seen := make(map[string]bool, len(markets))
+	for _, market := range markets {
+		if market = NormalizeMarket(market); market != "" && !seen[market] {
+			seen[market] = true
+			normalized = append(normalized, market)
+		}
+	}
+	return normalized
+}
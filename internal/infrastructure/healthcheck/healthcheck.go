@@ -0,0 +1,237 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"rates/internal/infrastructure/metrics"
+	"rates/pkg/logger"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+var (
+	log = logger.Logger().Named("health").Sugar()
+)
+
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+)
+
+// Check - проверка одной зависимости. Critical проверки определяют готовность сервиса к запросам,
+// остальные только отображаются в отчете и в статусе своего gRPC сервиса
+type Check struct {
+	Name     string
+	Critical bool
+	Probe    func(ctx context.Context) error
+}
+
+// Result - результат последней проверки зависимости
+type Result struct {
+	Status    string    `json:"status"`
+	Critical  bool      `json:"critical"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Config struct {
+	Interval time.Duration
+	// Ограничение времени одной проверки
+	Timeout time.Duration
+	// gRPC сервисы, статус которых зависит от всех critical проверок
+	Services []string
+}
+
+// Checker периодически выполняет проверки и переключает статусы grpc_health_v1.
+// Каждая проверка публикуется как отдельный gRPC сервис с именем проверки
+type Checker struct {
+	server *health.Server
+	cfg    Config
+	checks []Check
+
+	mu       sync.RWMutex
+	results  map[string]Result
+	shutdown bool
+}
+
+func New(server *health.Server, cfg Config, checks ...Check) *Checker {
+	c := &Checker{
+		server:  server,
+		cfg:     cfg,
+		checks:  checks,
+		results: make(map[string]Result, len(checks)),
+	}
+	// До первой проверки сервис не готов
+	for _, service := range c.cfg.Services {
+		server.SetServingStatus(service, healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return c
+}
+
+// Run выполняет проверки сразу и затем каждые Interval до отмены ctx
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.CheckAll(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// CheckAll выполняет все проверки параллельно и обновляет статусы
+func (c *Checker) CheckAll(ctx context.Context) {
+	results := make([]Result, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.probe(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.shutdown {
+		return
+	}
+
+	ready := true
+	for i, check := range c.checks {
+		result := results[i]
+		if prev, ok := c.results[check.Name]; !ok || prev.Status != result.Status {
+			if result.Status == StatusUp {
+				log.Infof("Dependency %s is up", check.Name)
+			} else {
+				log.Warnf("Dependency %s is down: %s", check.Name, result.Error)
+			}
+		}
+		c.results[check.Name] = result
+		c.server.SetServingStatus(check.Name, servingStatus(result.Status == StatusUp))
+		metrics.HealthCheckStatus(check.Name, result.Status == StatusUp)
+		if check.Critical && result.Status != StatusUp {
+			ready = false
+		}
+	}
+	for _, service := range c.cfg.Services {
+		c.server.SetServingStatus(service, servingStatus(ready))
+	}
+}
+
+func (c *Checker) probe(ctx context.Context, check Check) Result {
+	probeCtx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	result := Result{Status: StatusUp, Critical: check.Critical, CheckedAt: time.Now()}
+	if err := check.Probe(probeCtx); err != nil {
+		result.Status, result.Error = StatusDown, err.Error()
+	}
+	return result
+}
+
+// Shutdown переводит все сервисы в NOT_SERVING, чтобы балансировщики перестали направлять запросы
+// до остановки сервера. Последующие проверки статусы не меняют
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdown = true
+	c.server.Shutdown()
+}
+
+// Ready сообщает, прошли ли все critical проверки
+func (c *Checker) Ready() bool {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.ready()
+}
+
+func (c *Checker) ready() bool {
+	if c.shutdown {
+		return false
+	}
+	for _, check := range c.checks {
+		result, ok := c.results[check.Name]
+		if check.Critical && (!ok || result.Status != StatusUp) {
+			return false
+		}
+	}
+	return true
+}
+
+// report - тело ответа /readyz
+type report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks"`
+}
+
+// LiveHandler отвечает на /healthz: процесс работает и обрабатывает HTTP запросы
+func (c *Checker) LiveHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, report{Status: StatusUp})
+	})
+}
+
+// ReadyHandler отвечает на /readyz: 200, если все critical проверки прошли, иначе 503
+func (c *Checker) ReadyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		c.mu.RLock()
+		resp := report{Status: StatusUp, Checks: make(map[string]Result, len(c.results))}
+		for name, result := range c.results {
+			resp.Checks[name] = result
+		}
+		ready := c.ready()
+		c.mu.RUnlock()
+
+		code := http.StatusOK
+		if !ready {
+			resp.Status, code = StatusDown, http.StatusServiceUnavailable
+		}
+		writeJSON(w, code, resp)
+	})
+}
+
+func writeJSON(w http.ResponseWriter, code int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(body); err != nil {
+		log.Errorf("Failed to write health report: %v", err)
+	}
+}
+
+func servingStatus(up bool) healthpb.HealthCheckResponse_ServingStatus {
+	if up {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
+}
+
+// PingProbe проверяет соединение с базой данных
+func PingProbe(db interface {
+	PingContext(ctx context.Context) error
+}) func(ctx context.Context) error {
+	return db.PingContext
+}
+
+// DialProbe проверяет, что по адресу принимаются TCP соединения
+func DialProbe(address string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
@@ -0,0 +1,99 @@
+package healthcheck
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func servingOf(t *testing.T, server *health.Server, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := server.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.GetStatus()
+}
+
+func readyz(t *testing.T, c *Checker) (int, report) {
+	rec := httptest.NewRecorder()
+	c.ReadyHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	var resp report
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	return rec.Code, resp
+}
+
+func TestChecker(t *testing.T) {
+	var dbErr, otelErr error
+	server := health.NewServer()
+	c := New(server, Config{Timeout: time.Second, Services: []string{"rates"}},
+		Check{Name: "postgres", Critical: true, Probe: func(ctx context.Context) error { return dbErr }},
+		Check{Name: "otel", Probe: func(ctx context.Context) error { return otelErr }},
+	)
+
+	// До первой проверки сервис не готов
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingOf(t, server, "rates"))
+	require.False(t, c.Ready())
+
+	c.CheckAll(context.Background())
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingOf(t, server, "rates"))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingOf(t, server, "postgres"))
+	code, _ := readyz(t, c)
+	require.Equal(t, http.StatusOK, code)
+
+	// Недоступный экспортер не влияет на готовность
+	otelErr = errors.New("connection refused")
+	c.CheckAll(context.Background())
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingOf(t, server, "otel"))
+	require.Equal(t, healthpb.HealthCheckResponse_SERVING, servingOf(t, server, "rates"))
+	require.True(t, c.Ready())
+
+	// Недоступная база данных снимает сервис с обслуживания
+	dbErr = errors.New("db is down")
+	c.CheckAll(context.Background())
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingOf(t, server, "rates"))
+	code, resp := readyz(t, c)
+	require.Equal(t, http.StatusServiceUnavailable, code)
+	require.Equal(t, StatusDown, resp.Status)
+	require.Equal(t, "db is down", resp.Checks["postgres"].Error)
+
+	// После остановки проверки статус не возвращают
+	dbErr, otelErr = nil, nil
+	c.Shutdown()
+	c.CheckAll(context.Background())
+	require.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, servingOf(t, server, "rates"))
+	require.False(t, c.Ready())
+
+	// Процесс жив независимо от зависимостей
+	rec := httptest.NewRecorder()
+	c.LiveHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestChecker_Timeout(t *testing.T) {
+	c := New(health.NewServer(), Config{Timeout: 10 * time.Millisecond},
+		Check{Name: "slow", Critical: true, Probe: func(ctx context.Context) error {
+			<-ctx.Done()
+			return ctx.Err()
+		}},
+	)
+	c.CheckAll(context.Background())
+	require.False(t, c.Ready())
+}
+
+func TestDialProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+
+	require.NoError(t, DialProbe(addr)(context.Background()))
+
+	listener.Close()
+	require.Error(t, DialProbe(addr)(context.Background()))
+}
@@ -0,0 +1,244 @@
+package httpclient
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"rates/internal/infrastructure/metrics"
+	"rates/pkg/logger"
+	"strconv"
+	"time"
+
+	"github.com/sony/gobreaker"
+)
+
+var (
+	log = logger.Logger().Named("httpclient").Sugar()
+)
+
+// ErrCircuitOpen возвращается без запроса к API, пока предохранитель разомкнут
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// Значения по умолчанию для незаданных полей Config
+const (
+	DefaultTimeout         = 5 * time.Second
+	DefaultBaseBackoff     = 200 * time.Millisecond
+	DefaultMaxBackoff      = 2 * time.Second
+	DefaultBreakerFailures = 5
+	DefaultBreakerTimeout  = 30 * time.Second
+)
+
+type Config struct {
+	// Name - имя внешнего API в метриках и логах
+	Name string
+	// Timeout - ограничение одной попытки. Общий срок задает контекст запроса
+	Timeout time.Duration
+	// MaxRetries - количество повторов после первой попытки
+	MaxRetries int
+	// BaseBackoff и MaxBackoff - границы экспоненциальной паузы между попытками
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// BreakerFailures - количество ошибок подряд, после которого предохранитель размыкается
+	BreakerFailures uint32
+	// BreakerTimeout - время, через которое разомкнутый предохранитель пропускает пробный запрос
+	BreakerTimeout time.Duration
+}
+
+// Client - HTTP клиент с таймаутом попытки, повторами с экспоненциальной паузой и предохранителем.
+// Повторяются сетевые ошибки, ответы 5xx и 429. После исчерпания повторов
+// возвращается последний ответ, чтобы вызывающий код мог разобрать статус
+type Client struct {
+	client  *http.Client
+	cfg     Config
+	breaker *gobreaker.CircuitBreaker
+	sleep   func(ctx context.Context, d time.Duration) error
+}
+
+func New(cfg Config) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultTimeout
+	}
+	if cfg.BaseBackoff <= 0 {
+		cfg.BaseBackoff = DefaultBaseBackoff
+	}
+	if cfg.MaxBackoff < cfg.BaseBackoff {
+		cfg.MaxBackoff = max(DefaultMaxBackoff, cfg.BaseBackoff)
+	}
+	if cfg.BreakerFailures == 0 {
+		cfg.BreakerFailures = DefaultBreakerFailures
+	}
+	if cfg.BreakerTimeout <= 0 {
+		cfg.BreakerTimeout = DefaultBreakerTimeout
+	}
+
+	breaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:    cfg.Name,
+		Timeout: cfg.BreakerTimeout,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= cfg.BreakerFailures
+		},
+		// Отмена запроса клиентом не говорит о недоступности API
+		IsSuccessful: func(err error) bool {
+			return err == nil || errors.Is(err, context.Canceled)
+		},
+		OnStateChange: func(name string, from, to gobreaker.State) {
+			log.Warnf("Circuit breaker %s changed state from %s to %s", name, from, to)
+			metrics.CircuitBreakerState(name, int(to))
+		},
+	})
+	metrics.CircuitBreakerState(cfg.Name, int(gobreaker.StateClosed))
+
+	return &Client{
+		client:  &http.Client{},
+		cfg:     cfg,
+		breaker: breaker,
+		sleep:   sleepContext,
+	}
+}
+
+// retryableStatusError - ответ API, после которого запрос можно повторить
+type retryableStatusError struct {
+	resp *http.Response
+}
+
+func (e *retryableStatusError) Error() string {
+	return fmt.Sprintf("retryable status %d", e.resp.StatusCode)
+}
+
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	// Запрос с телом можно повторить, только если тело можно получить заново
+	retries := c.cfg.MaxRetries
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		retries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(req, attempt)
+		if err == nil {
+			return resp, nil
+		}
+
+		var statusErr *retryableStatusError
+		isStatus := errors.As(err, &statusErr)
+		if attempt >= retries || ctx.Err() != nil || errors.Is(err, ErrCircuitOpen) {
+			if isStatus {
+				// Повторы исчерпаны - вызывающий код сам разбирает статус ответа
+				return statusErr.resp, nil
+			}
+			return nil, err
+		}
+
+		pause := c.backoff(attempt)
+		reason := "network"
+		if isStatus {
+			reason = strconv.Itoa(statusErr.resp.StatusCode)
+			if retryAfter := parseRetryAfter(statusErr.resp); retryAfter > pause {
+				pause = min(retryAfter, c.cfg.MaxBackoff)
+			}
+			drain(statusErr.resp)
+		}
+		metrics.RetryHTTPRequest(c.cfg.Name, reason)
+		log.Warnf("Retrying request to %s in %s after attempt %d: %v", c.cfg.Name, pause, attempt+1, err)
+
+		if err := c.sleep(ctx, pause); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// attempt выполняет одну попытку через предохранитель с ограничением по времени
+func (c *Client) attempt(req *http.Request, attempt int) (*http.Response, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), c.cfg.Timeout)
+
+	attemptReq := req.Clone(ctx)
+	if attempt > 0 && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, err
+		}
+		attemptReq.Body = body
+	}
+
+	result, err := c.breaker.Execute(func() (interface{}, error) {
+		resp, err := c.client.Do(attemptReq)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests {
+			return nil, &retryableStatusError{resp: resp}
+		}
+		return resp, nil
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		cancel()
+		return nil, fmt.Errorf("%w: %s", ErrCircuitOpen, c.cfg.Name)
+	}
+
+	var statusErr *retryableStatusError
+	switch {
+	case errors.As(err, &statusErr):
+		statusErr.resp.Body = &cancelBody{ReadCloser: statusErr.resp.Body, cancel: cancel}
+		return nil, err
+	case err != nil:
+		cancel()
+		return nil, err
+	}
+
+	// Контекст попытки отменяется после чтения ответа, а не при возврате из Do
+	resp := result.(*http.Response)
+	resp.Body = &cancelBody{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// backoff возвращает паузу перед повтором: BaseBackoff*2^attempt, ограниченную MaxBackoff,
+// из которой случайна вторая половина, чтобы клиенты не повторяли запросы одновременно
+func (c *Client) backoff(attempt int) time.Duration {
+	ceiling := c.cfg.MaxBackoff
+	if attempt < 30 {
+		ceiling = min(c.cfg.BaseBackoff<<attempt, c.cfg.MaxBackoff)
+	}
+	half := ceiling / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
+
+// parseRetryAfter читает задержку из заголовка Retry-After в секундах
+func parseRetryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil || seconds <= 0 {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type cancelBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
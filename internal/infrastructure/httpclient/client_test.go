@@ -0,0 +1,130 @@
+package httpclient
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(cfg Config) (*Client, *[]time.Duration) {
+	client := New(cfg)
+	pauses := &[]time.Duration{}
+	client.sleep = func(ctx context.Context, d time.Duration) error {
+		*pauses = append(*pauses, d)
+		return ctx.Err()
+	}
+	return client, pauses
+}
+
+func get(t *testing.T, client *Client, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, nil)
+	require.NoError(t, err)
+	return client.Do(req)
+}
+
+func TestClient_RetriesRetryableStatuses(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch calls.Add(1) {
+		case 1:
+			w.WriteHeader(http.StatusBadGateway)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			_, _ = w.Write([]byte("ok"))
+		}
+	}))
+	defer srv.Close()
+
+	client, pauses := newTestClient(Config{Name: "test", MaxRetries: 3, BaseBackoff: 100 * time.Millisecond,
+		MaxBackoff: 5 * time.Second})
+
+	resp, err := get(t, client, srv.URL)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	require.Equal(t, "ok", string(body))
+	require.Equal(t, int32(3), calls.Load())
+
+	// Первая пауза - от половины до целого BaseBackoff, вторая взята из Retry-After
+	require.Len(t, *pauses, 2)
+	require.GreaterOrEqual(t, (*pauses)[0], 50*time.Millisecond)
+	require.LessOrEqual(t, (*pauses)[0], 100*time.Millisecond)
+	require.Equal(t, time.Second, (*pauses)[1])
+}
+
+func TestClient_ReturnsLastResponseWhenRetriesExhausted(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	client, _ := newTestClient(Config{Name: "test", MaxRetries: 2, BreakerFailures: 10})
+
+	resp, err := get(t, client, srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	require.Equal(t, int32(3), calls.Load())
+}
+
+func TestClient_DoesNotRetryClientErrors(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	client, _ := newTestClient(Config{Name: "test", MaxRetries: 3})
+
+	resp, err := get(t, client, srv.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+	require.Equal(t, int32(1), calls.Load())
+}
+
+func TestClient_CircuitBreakerOpens(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	client, _ := newTestClient(Config{Name: "test", MaxRetries: 5, BreakerFailures: 2, BreakerTimeout: time.Minute})
+
+	// Две ошибки подряд размыкают предохранитель, дальше запросы к API не уходят
+	_, err := get(t, client, srv.URL)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, int32(2), calls.Load())
+
+	_, err = get(t, client, srv.URL)
+	require.ErrorIs(t, err, ErrCircuitOpen)
+	require.Equal(t, int32(2), calls.Load())
+}
+
+func TestClient_AttemptTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer srv.Close()
+
+	client, pauses := newTestClient(Config{Name: "test", Timeout: 20 * time.Millisecond, MaxRetries: 1})
+
+	_, err := get(t, client, srv.URL)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Len(t, *pauses, 1)
+}
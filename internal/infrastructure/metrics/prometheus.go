@@ -7,11 +7,14 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func Listen(address string) error {
+// Handler обслуживает /metrics и дополнительные обработчики handlers по путям
+func Handler(handlers map[string]http.Handler) http.Handler {
 	mux := http.NewServeMux()
 	mux.Handle("/metrics", promhttp.Handler())
-
-	return http.ListenAndServe(address, mux)
+	for path, handler := range handlers {
+		mux.Handle(path, handler)
+	}
+	return mux
 }
 
 var (
@@ -20,16 +23,16 @@ var (
 			Name: "http_requests_total",
 			Help: "Total number of HTTP request to external API",
 		},
-		[]string{"status"},
+		[]string{"source", "status"},
 	)
 
 	requestDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "get_rates_duration_seconds",
-			Help:    "Duration of GetRates execution",
+			Help:    "Duration of requests to rate sources",
 			Buckets: prometheus.DefBuckets,
 		},
-		[]string{"step"},
+		[]string{"source", "step"},
 	)
 
 	dbOperationsTotal = prometheus.NewCounterVec(
@@ -54,6 +57,87 @@ var (
 		},
 	)
 
+	pollTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "poll_total",
+			Help: "Total number of scheduled polls of rate source",
+		},
+		[]string{"market", "status"},
+	)
+
+	cacheRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "cache_requests_total",
+			Help: "Total number of rate cache lookups",
+		},
+		[]string{"market", "result"},
+	)
+
+	sourceRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "source_requests_total",
+			Help: "Total number of requests to rate sources",
+		},
+		[]string{"source", "status"},
+	)
+
+	sourceUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "source_up",
+			Help: "Whether the last request to rate source succeeded",
+		},
+		[]string{"source"},
+	)
+
+	circuitBreakerState = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "State of upstream circuit breaker: 0 - closed, 1 - half-open, 2 - open",
+		},
+		[]string{"name"},
+	)
+
+	httpClientRetriesTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_retries_total",
+			Help: "Total number of retried requests to external API",
+		},
+		[]string{"name", "reason"},
+	)
+
+	grpcRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "Total number of gRPC requests handled by the service",
+		},
+		[]string{"method", "code", "client"},
+	)
+
+	healthCheckStatus = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "health_check_status",
+			Help: "Result of the last dependency health check: 1 - up, 0 - down",
+		},
+		[]string{"check"},
+	)
+
+	grpcRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Duration of gRPC request handling in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method"},
+	)
+
+	configReloadsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "config_reloads_total",
+			Help: "Total number of configuration reloads by result",
+		},
+		[]string{"result"},
+	)
+
 	dbOperationsDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:    "db_operation_duration_seconds",
@@ -66,15 +150,17 @@ var (
 
 func init() {
 	prometheus.MustRegister(httpRequestTotal, requestDuration, dbOperationsTotal,
-		requestsProcessedTotal, requestTotal, dbOperationsDuration)
+		requestsProcessedTotal, requestTotal, dbOperationsDuration, pollTotal, cacheRequestsTotal,
+		sourceRequestsTotal, sourceUp, circuitBreakerState, httpClientRetriesTotal,
+		grpcRequestsTotal, grpcRequestDuration, healthCheckStatus, configReloadsTotal)
 }
 
-func StatusRequestToGarantex(status string) {
-	httpRequestTotal.WithLabelValues(status).Inc()
+func StatusRequestToSource(source, status string) {
+	httpRequestTotal.WithLabelValues(source, status).Inc()
 }
 
-func TimeRequestToGarantex(step string, duration float64) {
-	requestDuration.WithLabelValues(step).Observe(duration)
+func TimeRequestToSource(source, step string, duration float64) {
+	requestDuration.WithLabelValues(source, step).Observe(duration)
 }
 
 func StatusRequestToDB(operation, status string) {
@@ -92,3 +178,50 @@ func CountRequestToService() {
 func TimeRequestToDB(operation string, duration float64) {
 	dbOperationsDuration.WithLabelValues(operation).Observe(duration)
 }
+
+func StatusPoll(market, status string) {
+	pollTotal.WithLabelValues(market, status).Inc()
+}
+
+func CacheHit(market string) {
+	cacheRequestsTotal.WithLabelValues(market, "hit").Inc()
+}
+
+func CacheMiss(market string) {
+	cacheRequestsTotal.WithLabelValues(market, "miss").Inc()
+}
+
+// StatusSource фиксирует результат запроса к источнику и его текущую доступность
+func StatusSource(source, status string) {
+	sourceRequestsTotal.WithLabelValues(source, status).Inc()
+	if status == "success" {
+		sourceUp.WithLabelValues(source).Set(1)
+	} else {
+		sourceUp.WithLabelValues(source).Set(0)
+	}
+}
+
+func CircuitBreakerState(name string, state int) {
+	circuitBreakerState.WithLabelValues(name).Set(float64(state))
+}
+
+func RetryHTTPRequest(name, reason string) {
+	httpClientRetriesTotal.WithLabelValues(name, reason).Inc()
+}
+
+func ObserveGRPCRequest(method, code, client string, duration float64) {
+	grpcRequestsTotal.WithLabelValues(method, code, client).Inc()
+	grpcRequestDuration.WithLabelValues(method).Observe(duration)
+}
+
+func HealthCheckStatus(check string, up bool) {
+	value := 0.0
+	if up {
+		value = 1
+	}
+	healthCheckStatus.WithLabelValues(check).Set(value)
+}
+
+func CountConfigReload(result string) {
+	configReloadsTotal.WithLabelValues(result).Inc()
+}
@@ -14,7 +14,8 @@ import (
 
 // SetUpOTelSDK инициализирует OpenTelemetry SDK и возвращает функцию завершения работы (shutdown).
 // Эта функция настраивает провайдер трассировки, экспортирует трассы через OTLP HTTP и регистрирует провайдер.
-func SetUpOTelSDK(ctx context.Context) (shutdown func(context.Context) error, err error) {
+// Пустой endpoint оставляет адрес коллектора из переменных окружения OTEL_EXPORTER_OTLP_*.
+func SetUpOTelSDK(ctx context.Context, endpoint string) (shutdown func(context.Context) error, err error) {
 	// Список функций, которые необходимо вызвать для корректного завершения работы (например, закрытие провайдера).
 	var shutdownFuncs []func(context.Context) error
 
@@ -37,7 +38,7 @@ func SetUpOTelSDK(ctx context.Context) (shutdown func(context.Context) error, er
 		err = errors.Join(inErr, shutdown(ctx))
 	}
 	// Создание провайдера трассировки.
-	tracerProvider, err := newTraceProvider(ctx)
+	tracerProvider, err := newTraceProvider(ctx, endpoint)
 	if err != nil {
 		handleErr(err)
 		return
@@ -51,9 +52,13 @@ func SetUpOTelSDK(ctx context.Context) (shutdown func(context.Context) error, er
 }
 
 // newTraceProvider создает новый провайдер трассировки с OTLP HTTP экспортером.
-func newTraceProvider(ctx context.Context) (*trace.TracerProvider, error) {
+func newTraceProvider(ctx context.Context, endpoint string) (*trace.TracerProvider, error) {
+	var opts []otlptracehttp.Option
+	if endpoint != "" {
+		opts = append(opts, otlptracehttp.WithEndpointURL(endpoint))
+	}
 	// Создание экспортера трассировки, который отправляет данные через OTLP по HTTP.
-	traceExporter, err := otlptracehttp.New(ctx) // Возврат ошибки, если экспортер не удалось создать.
+	traceExporter, err := otlptracehttp.New(ctx, opts...) // Возврат ошибки, если экспортер не удалось создать.
 
 	if err != nil {
 		return nil, err
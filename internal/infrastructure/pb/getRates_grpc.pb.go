@@ -1,8 +1,8 @@
-//protoc --go_out=. --go-grpc_out=. getRates.proto
+//protoc -I . -I third_party --go_out=. --go-grpc_out=. --grpc-gateway_out=. --openapiv2_out=. getRates.proto
 
 // Code generated by protoc-gen-go-grpc. DO NOT EDIT.
 // versions:
-// - protoc-gen-go-grpc v1.4.0
+// - protoc-gen-go-grpc v1.5.1
 // - protoc             v3.21.12
 // source: getRates.proto
 
@@ -17,11 +17,17 @@ import (
 
 // This is a compile-time assertion to ensure that this generated file
 // is compatible with the grpc package it is being compiled against.
-// Requires gRPC-Go v1.62.0 or later.
-const _ = grpc.SupportPackageIsVersion8
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
 
 const (
-	GetRateser_GetRates_FullMethodName = "/pbPackage.GetRateser/GetRates"
+	GetRateser_GetRates_FullMethodName           = "/pbPackage.GetRateser/GetRates"
+	GetRateser_GetOrderBook_FullMethodName       = "/pbPackage.GetRateser/GetOrderBook"
+	GetRateser_GetHistory_FullMethodName         = "/pbPackage.GetRateser/GetHistory"
+	GetRateser_GetCandles_FullMethodName         = "/pbPackage.GetRateser/GetCandles"
+	GetRateser_SubscribeRates_FullMethodName     = "/pbPackage.GetRateser/SubscribeRates"
+	GetRateser_QuoteConversion_FullMethodName    = "/pbPackage.GetRateser/QuoteConversion"
+	GetRateser_GetAggregatedRates_FullMethodName = "/pbPackage.GetRateser/GetAggregatedRates"
 )
 
 // GetRateserClient is the client API for GetRateser service.
@@ -29,6 +35,12 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type GetRateserClient interface {
 	GetRates(ctx context.Context, in *RatesRequest, opts ...grpc.CallOption) (*RatesResponse, error)
+	GetOrderBook(ctx context.Context, in *OrderBookRequest, opts ...grpc.CallOption) (*OrderBookResponse, error)
+	GetHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error)
+	GetCandles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (*CandlesResponse, error)
+	SubscribeRates(ctx context.Context, in *RatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RatesResponse], error)
+	QuoteConversion(ctx context.Context, in *ConversionRequest, opts ...grpc.CallOption) (*ConversionResponse, error)
+	GetAggregatedRates(ctx context.Context, in *AggregatedRatesRequest, opts ...grpc.CallOption) (*AggregatedRatesResponse, error)
 }
 
 type getRateserClient struct {
@@ -49,22 +61,119 @@ func (c *getRateserClient) GetRates(ctx context.Context, in *RatesRequest, opts
 	return out, nil
 }
 
+func (c *getRateserClient) GetOrderBook(ctx context.Context, in *OrderBookRequest, opts ...grpc.CallOption) (*OrderBookResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OrderBookResponse)
+	err := c.cc.Invoke(ctx, GetRateser_GetOrderBook_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *getRateserClient) GetHistory(ctx context.Context, in *HistoryRequest, opts ...grpc.CallOption) (*HistoryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(HistoryResponse)
+	err := c.cc.Invoke(ctx, GetRateser_GetHistory_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *getRateserClient) GetCandles(ctx context.Context, in *CandlesRequest, opts ...grpc.CallOption) (*CandlesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CandlesResponse)
+	err := c.cc.Invoke(ctx, GetRateser_GetCandles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *getRateserClient) SubscribeRates(ctx context.Context, in *RatesRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[RatesResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &GetRateser_ServiceDesc.Streams[0], GetRateser_SubscribeRates_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[RatesRequest, RatesResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GetRateser_SubscribeRatesClient = grpc.ServerStreamingClient[RatesResponse]
+
+func (c *getRateserClient) QuoteConversion(ctx context.Context, in *ConversionRequest, opts ...grpc.CallOption) (*ConversionResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ConversionResponse)
+	err := c.cc.Invoke(ctx, GetRateser_QuoteConversion_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *getRateserClient) GetAggregatedRates(ctx context.Context, in *AggregatedRatesRequest, opts ...grpc.CallOption) (*AggregatedRatesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AggregatedRatesResponse)
+	err := c.cc.Invoke(ctx, GetRateser_GetAggregatedRates_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // GetRateserServer is the server API for GetRateser service.
 // All implementations must embed UnimplementedGetRateserServer
-// for forward compatibility
+// for forward compatibility.
 type GetRateserServer interface {
 	GetRates(context.Context, *RatesRequest) (*RatesResponse, error)
+	GetOrderBook(context.Context, *OrderBookRequest) (*OrderBookResponse, error)
+	GetHistory(context.Context, *HistoryRequest) (*HistoryResponse, error)
+	GetCandles(context.Context, *CandlesRequest) (*CandlesResponse, error)
+	SubscribeRates(*RatesRequest, grpc.ServerStreamingServer[RatesResponse]) error
+	QuoteConversion(context.Context, *ConversionRequest) (*ConversionResponse, error)
+	GetAggregatedRates(context.Context, *AggregatedRatesRequest) (*AggregatedRatesResponse, error)
 	mustEmbedUnimplementedGetRateserServer()
 }
 
-// UnimplementedGetRateserServer must be embedded to have forward compatible implementations.
-type UnimplementedGetRateserServer struct {
-}
+// UnimplementedGetRateserServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedGetRateserServer struct{}
 
 func (UnimplementedGetRateserServer) GetRates(context.Context, *RatesRequest) (*RatesResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method GetRates not implemented")
 }
+func (UnimplementedGetRateserServer) GetOrderBook(context.Context, *OrderBookRequest) (*OrderBookResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOrderBook not implemented")
+}
+func (UnimplementedGetRateserServer) GetHistory(context.Context, *HistoryRequest) (*HistoryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetHistory not implemented")
+}
+func (UnimplementedGetRateserServer) GetCandles(context.Context, *CandlesRequest) (*CandlesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCandles not implemented")
+}
+func (UnimplementedGetRateserServer) SubscribeRates(*RatesRequest, grpc.ServerStreamingServer[RatesResponse]) error {
+	return status.Errorf(codes.Unimplemented, "method SubscribeRates not implemented")
+}
+func (UnimplementedGetRateserServer) QuoteConversion(context.Context, *ConversionRequest) (*ConversionResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QuoteConversion not implemented")
+}
+func (UnimplementedGetRateserServer) GetAggregatedRates(context.Context, *AggregatedRatesRequest) (*AggregatedRatesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetAggregatedRates not implemented")
+}
 func (UnimplementedGetRateserServer) mustEmbedUnimplementedGetRateserServer() {}
+func (UnimplementedGetRateserServer) testEmbeddedByValue()                    {}
 
 // UnsafeGetRateserServer may be embedded to opt out of forward compatibility for this service.
 // Use of this interface is not recommended, as added methods to GetRateserServer will
@@ -74,6 +183,13 @@ type UnsafeGetRateserServer interface {
 }
 
 func RegisterGetRateserServer(s grpc.ServiceRegistrar, srv GetRateserServer) {
+	// If the following call pancis, it indicates UnimplementedGetRateserServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
 	s.RegisterService(&GetRateser_ServiceDesc, srv)
 }
 
@@ -95,6 +211,107 @@ func _GetRateser_GetRates_Handler(srv interface{}, ctx context.Context, dec func
 	return interceptor(ctx, in, info, handler)
 }
 
+func _GetRateser_GetOrderBook_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(OrderBookRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GetRateserServer).GetOrderBook(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GetRateser_GetOrderBook_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GetRateserServer).GetOrderBook(ctx, req.(*OrderBookRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GetRateser_GetHistory_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HistoryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GetRateserServer).GetHistory(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GetRateser_GetHistory_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GetRateserServer).GetHistory(ctx, req.(*HistoryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GetRateser_GetCandles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CandlesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GetRateserServer).GetCandles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GetRateser_GetCandles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GetRateserServer).GetCandles(ctx, req.(*CandlesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GetRateser_SubscribeRates_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(RatesRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(GetRateserServer).SubscribeRates(m, &grpc.GenericServerStream[RatesRequest, RatesResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type GetRateser_SubscribeRatesServer = grpc.ServerStreamingServer[RatesResponse]
+
+func _GetRateser_QuoteConversion_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConversionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GetRateserServer).QuoteConversion(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GetRateser_QuoteConversion_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GetRateserServer).QuoteConversion(ctx, req.(*ConversionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _GetRateser_GetAggregatedRates_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AggregatedRatesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(GetRateserServer).GetAggregatedRates(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: GetRateser_GetAggregatedRates_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(GetRateserServer).GetAggregatedRates(ctx, req.(*AggregatedRatesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // GetRateser_ServiceDesc is the grpc.ServiceDesc for GetRateser service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -106,7 +323,33 @@ var GetRateser_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "GetRates",
 			Handler:    _GetRateser_GetRates_Handler,
 		},
+		{
+			MethodName: "GetOrderBook",
+			Handler:    _GetRateser_GetOrderBook_Handler,
+		},
+		{
+			MethodName: "GetHistory",
+			Handler:    _GetRateser_GetHistory_Handler,
+		},
+		{
+			MethodName: "GetCandles",
+			Handler:    _GetRateser_GetCandles_Handler,
+		},
+		{
+			MethodName: "QuoteConversion",
+			Handler:    _GetRateser_QuoteConversion_Handler,
+		},
+		{
+			MethodName: "GetAggregatedRates",
+			Handler:    _GetRateser_GetAggregatedRates_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "SubscribeRates",
+			Handler:       _GetRateser_SubscribeRates_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "getRates.proto",
 }
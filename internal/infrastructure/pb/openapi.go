@@ -0,0 +1,8 @@
+package __
+
+import _ "embed"
+
+// OpenAPI - спецификация REST шлюза, сгенерированная protoc-gen-openapiv2
+//
+//go:embed getRates.swagger.json
+var OpenAPI []byte
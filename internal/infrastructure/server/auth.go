@@ -0,0 +1,98 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"rates/internal/auth"
+	"rates/internal/controller"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// APIKeyHeader - метаданные с API ключом клиента
+const APIKeyHeader = "x-api-key"
+
+// healthService не требует ключа, чтобы проверки оркестратора работали без него
+const healthService = "/grpc.health.v1.Health/"
+
+// authenticate проверяет ключ из метаданных и сохраняет клиента в информации о запросе
+func authenticate(ctx context.Context, authenticator *auth.Authenticator, method string) error {
+	if strings.HasPrefix(method, healthService) {
+		return nil
+	}
+
+	var key string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(APIKeyHeader); len(values) > 0 {
+			key = values[0]
+		}
+	}
+
+	apiKey, delay, err := authenticator.Authenticate(ctx, key)
+	if info := infoFromContext(ctx); info != nil && apiKey.ClientID != "" {
+		info.client = apiKey.ClientID
+	}
+	if err != nil {
+		return authStatusError(err, delay)
+	}
+	return nil
+}
+
+func authStatusError(err error, delay time.Duration) error {
+	var (
+		code   codes.Code
+		reason string
+	)
+	switch {
+	case errors.Is(err, auth.ErrMissingKey):
+		code, reason = codes.Unauthenticated, "MISSING_API_KEY"
+	case errors.Is(err, auth.ErrInvalidKey):
+		code, reason = codes.Unauthenticated, "INVALID_API_KEY"
+	case errors.Is(err, auth.ErrQuotaExceeded):
+		code, reason = codes.ResourceExhausted, "QUOTA_EXCEEDED"
+	case errors.Is(err, auth.ErrTooManyFailures):
+		code, reason = codes.ResourceExhausted, "TOO_MANY_INVALID_KEYS"
+	default:
+		log.Errorf("Failed to check api key: %v", err)
+		return status.Error(codes.Unavailable, "api key check is unavailable")
+	}
+
+	st := status.New(code, err.Error())
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{Reason: reason, Domain: controller.ErrorDomain}}
+	if delay > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(delay)})
+	}
+	if withDetails, err := st.WithDetails(details...); err == nil {
+		st = withDetails
+	}
+	return st.Err()
+}
+
+func unaryAuth(authenticator *auth.Authenticator) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler) (interface{}, error) {
+		if err := authenticate(ctx, authenticator, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func streamAuth(authenticator *auth.Authenticator) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler) error {
+		if err := authenticate(ss.Context(), authenticator, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rates/internal/auth"
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryAuth(t *testing.T) {
+	authenticator := auth.NewAuthenticator(auth.NewStaticStore(map[string]entity.APIKey{
+		"secret": {ClientID: "billing", RateLimit: 1, Burst: 1},
+	}), auth.Config{})
+	interceptor := unaryAuth(authenticator)
+
+	var client string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		client = ClientIDFromContext(ctx)
+		return nil, nil
+	}
+	call := func(key string, info *grpc.UnaryServerInfo) error {
+		ctx := withRequestID(context.Background())
+		if key != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(APIKeyHeader, key))
+		}
+		_, err := interceptor(ctx, nil, info, handler)
+		return err
+	}
+
+	require.Equal(t, codes.Unauthenticated, status.Code(call("", testInfo)))
+	require.Equal(t, codes.Unauthenticated, status.Code(call("guess", testInfo)))
+
+	require.NoError(t, call("secret", testInfo))
+	require.Equal(t, "billing", client)
+
+	// Квота исчерпана, клиенту сообщается время повтора
+	err := call("secret", testInfo)
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	var retry *errdetails.RetryInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.RetryInfo); ok {
+			retry = d
+		}
+	}
+	require.NotNil(t, retry)
+	require.Positive(t, retry.GetRetryDelay().AsDuration())
+
+	// Проверка здоровья доступна без ключа
+	require.NoError(t, call("", &grpc.UnaryServerInfo{FullMethod: "/grpc.health.v1.Health/Check"}))
+}
+
+func TestAuthStatusError_TooManyFailures(t *testing.T) {
+	st := status.Convert(authStatusError(auth.ErrTooManyFailures, time.Second))
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	var info *errdetails.ErrorInfo
+	for _, detail := range st.Details() {
+		if d, ok := detail.(*errdetails.ErrorInfo); ok {
+			info = d
+		}
+	}
+	require.NotNil(t, info)
+	require.Equal(t, "TOO_MANY_INVALID_KEYS", info.GetReason())
+}
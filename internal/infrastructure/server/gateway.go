@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"net/textproto"
+	"time"
+
+	pb "rates/internal/infrastructure/pb"
+
+	"github.com/grpc-ecosystem/grpc-gateway/v2/runtime"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// OpenAPIPath - путь спецификации REST шлюза
+const OpenAPIPath = "/v1/openapi.json"
+
+// Gateway создает HTTP сервер REST/JSON шлюза. Запросы проксируются в gRPC сервер по адресу grpcAddr,
+// поэтому проходят ту же цепочку перехватчиков, что и gRPC вызовы. tlsConfig nil - подключение без шифрования
+func (s *Server) Gateway(ctx context.Context, grpcAddr string, tlsConfig *tls.Config) (*http.Server, error) {
+	creds := insecure.NewCredentials()
+	if tlsConfig != nil {
+		creds = credentials.NewTLS(tlsConfig)
+	}
+	conn, err := grpc.NewClient(grpcAddr, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("dial gRPC server: %w", err)
+	}
+
+	handler, err := newGatewayHandler(ctx, conn)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 5 * time.Second,
+	}
+	server.RegisterOnShutdown(func() {
+		conn.Close()
+	})
+	return server, nil
+}
+
+func newGatewayHandler(ctx context.Context, conn *grpc.ClientConn) (http.Handler, error) {
+	gwmux := runtime.NewServeMux(
+		runtime.WithIncomingHeaderMatcher(incomingHeader),
+		runtime.WithOutgoingHeaderMatcher(outgoingHeader),
+	)
+	if err := pb.RegisterGetRateserHandler(ctx, gwmux, conn); err != nil {
+		return nil, fmt.Errorf("register gateway: %w", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(OpenAPIPath, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write(pb.OpenAPI)
+	})
+	mux.Handle("/", gwmux)
+	return mux, nil
+}
+
+// incomingHeader дополнительно передает в gRPC идентификатор запроса и API ключ
+func incomingHeader(key string) (string, bool) {
+	switch textproto.CanonicalMIMEHeaderKey(key) {
+	case textproto.CanonicalMIMEHeaderKey(RequestIDHeader):
+		return RequestIDHeader, true
+	case textproto.CanonicalMIMEHeaderKey(APIKeyHeader):
+		return APIKeyHeader, true
+	}
+	return runtime.DefaultHeaderMatcher(key)
+}
+
+// outgoingHeader возвращает идентификатор запроса клиенту без префикса Grpc-Metadata-
+func outgoingHeader(key string) (string, bool) {
+	if key == RequestIDHeader {
+		return key, true
+	}
+	return fmt.Sprintf("%s%s", runtime.MetadataHeaderPrefix, key), true
+}
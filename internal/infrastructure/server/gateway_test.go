@@ -0,0 +1,100 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	pb "rates/internal/infrastructure/pb"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type stubRateser struct {
+	pb.UnimplementedGetRateserServer
+	requestID string
+	subject   string
+}
+
+func (s *stubRateser) GetRates(ctx context.Context, req *pb.RatesRequest) (*pb.RatesResponse, error) {
+	if req.GetMarket() != "usdtrub" {
+		return nil, status.Error(codes.InvalidArgument, "unknown market")
+	}
+	s.requestID = RequestIDFromContext(ctx)
+	s.subject = ClientSubjectFromContext(ctx)
+	return &pb.RatesResponse{
+		Market: req.GetMarket(),
+		Ask:    &pb.Order{Price: "95.5"},
+		Bid:    &pb.Order{Price: "95.1"},
+	}, nil
+}
+
+func newTestGateway(t *testing.T, stub *stubRateser) http.Handler {
+	listener := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(UnaryInterceptors(nil)...))
+	pb.RegisterGetRateserServer(srv, stub)
+	go srv.Serve(listener)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+
+	handler, err := newGatewayHandler(context.Background(), conn)
+	require.NoError(t, err)
+	return handler
+}
+
+func TestGateway_GetRates(t *testing.T) {
+	stub := &stubRateser{}
+	handler := newTestGateway(t, stub)
+
+	req := httptest.NewRequest(http.MethodGet, "/v1/rates?market=usdtrub", nil)
+	req.Header.Set("X-Request-Id", "req-1")
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "req-1", rec.Header().Get(RequestIDHeader))
+
+	var resp struct {
+		Market string
+		Ask    struct{ Price string }
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &resp))
+	require.Equal(t, "usdtrub", resp.Market)
+	require.Equal(t, "95.5", resp.Ask.Price)
+	// Идентификатор запроса доходит до gRPC обработчика
+	require.Equal(t, "req-1", stub.requestID)
+
+	// Коды gRPC переводятся в HTTP статусы
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/v1/rates?market=eurrub", nil))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestGateway_OpenAPI(t *testing.T) {
+	handler := newTestGateway(t, &stubRateser{})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, OpenAPIPath, nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	var spec struct {
+		Paths map[string]any
+	}
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &spec))
+	require.Contains(t, spec.Paths, "/v1/rates")
+}
@@ -0,0 +1,220 @@
+package server
+
+import (
+	"context"
+	"runtime/debug"
+	"time"
+
+	"rates/internal/auth"
+	"rates/internal/infrastructure/metrics"
+	"rates/pkg/logger"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDHeader - метаданные с идентификатором запроса во входящем запросе и в ответе
+const RequestIDHeader = "x-request-id"
+
+var (
+	accessLog = logger.Logger().Named("access")
+)
+
+// anonymousClient - метка клиента в логах и метриках для запросов без проверенного ключа
+const anonymousClient = "anonymous"
+
+type requestInfoKey struct{}
+
+// requestInfo заполняется перехватчиками по ходу обработки запроса
+type requestInfo struct {
+	id     string
+	client string
+	// subject проверенного клиентского сертификата при mTLS
+	subject string
+}
+
+func infoFromContext(ctx context.Context) *requestInfo {
+	info, _ := ctx.Value(requestInfoKey{}).(*requestInfo)
+	return info
+}
+
+// RequestIDFromContext возвращает идентификатор текущего запроса
+func RequestIDFromContext(ctx context.Context) string {
+	if info := infoFromContext(ctx); info != nil {
+		return info.id
+	}
+	return ""
+}
+
+// ClientIDFromContext возвращает клиента, чей API ключ прошел проверку
+func ClientIDFromContext(ctx context.Context) string {
+	if info := infoFromContext(ctx); info != nil {
+		return info.client
+	}
+	return ""
+}
+
+// ClientSubjectFromContext возвращает subject клиентского сертификата, проверенного при mTLS
+func ClientSubjectFromContext(ctx context.Context) string {
+	if info := infoFromContext(ctx); info != nil {
+		return info.subject
+	}
+	return ""
+}
+
+func clientLabel(ctx context.Context) string {
+	if client := ClientIDFromContext(ctx); client != "" {
+		return client
+	}
+	return anonymousClient
+}
+
+// UnaryInterceptors - цепочка обработки unary вызовов. Проверка ключа стоит после логирования и метрик,
+// чтобы отклоненные запросы тоже учитывались. Recovery стоит последним,
+// чтобы паника превращалась в Internal до логирования и метрик. authenticator nil отключает проверку ключей
+func UnaryInterceptors(authenticator *auth.Authenticator) []grpc.UnaryServerInterceptor {
+	interceptors := []grpc.UnaryServerInterceptor{
+		unaryRequestID,
+		unaryAccessLog,
+		unaryMetrics,
+	}
+	if authenticator != nil {
+		interceptors = append(interceptors, unaryAuth(authenticator))
+	}
+	return append(interceptors, unaryRecovery)
+}
+
+// StreamInterceptors - та же цепочка для потоковых вызовов
+func StreamInterceptors(authenticator *auth.Authenticator) []grpc.StreamServerInterceptor {
+	interceptors := []grpc.StreamServerInterceptor{
+		streamRequestID,
+		streamAccessLog,
+		streamMetrics,
+	}
+	if authenticator != nil {
+		interceptors = append(interceptors, streamAuth(authenticator))
+	}
+	return append(interceptors, streamRecovery)
+}
+
+// withRequestID берет идентификатор из метаданных клиента или создает новый и возвращает его в заголовке ответа.
+// Там же запоминается subject клиентского сертификата
+func withRequestID(ctx context.Context) context.Context {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(RequestIDHeader); len(values) > 0 {
+			id = values[0]
+		}
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, id))
+	return context.WithValue(ctx, requestInfoKey{}, &requestInfo{id: id, subject: clientSubject(ctx)})
+}
+
+func unaryRequestID(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+func streamRequestID(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	return handler(srv, &contextStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+func logAccess(ctx context.Context, method string, start time.Time, err error) {
+	fields := []zap.Field{
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+		zap.String("request_id", RequestIDFromContext(ctx)),
+		zap.String("client", clientLabel(ctx)),
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		fields = append(fields, zap.String("peer", p.Addr.String()))
+	}
+	if subject := ClientSubjectFromContext(ctx); subject != "" {
+		fields = append(fields, zap.String("client_subject", subject))
+	}
+	if err != nil {
+		accessLog.Warn("request failed", append(fields, zap.Error(err))...)
+		return
+	}
+	accessLog.Info("request handled", fields...)
+}
+
+func unaryAccessLog(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	logAccess(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamAccessLog(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	logAccess(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+func observe(ctx context.Context, method string, start time.Time, err error) {
+	metrics.CountRequestToService()
+	if err == nil {
+		metrics.CountSuccessRequestToService()
+	}
+	metrics.ObserveGRPCRequest(method, status.Code(err).String(), clientLabel(ctx), time.Since(start).Seconds())
+}
+
+func unaryMetrics(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	observe(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func streamMetrics(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	observe(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+func recoverPanic(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		log.Errorw("panic in gRPC handler", "method", method, "panic", r,
+			"request_id", RequestIDFromContext(ctx), "stack", string(debug.Stack()))
+		*err = status.Error(codes.Internal, "internal error")
+	}
+}
+
+func unaryRecovery(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo,
+	handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recoverPanic(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func streamRecovery(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo,
+	handler grpc.StreamHandler) (err error) {
+	defer recoverPanic(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+// contextStream подменяет контекст потока
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
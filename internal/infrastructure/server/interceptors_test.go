@@ -0,0 +1,64 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var testInfo = &grpc.UnaryServerInfo{FullMethod: "/pbPackage.GetRateser/GetRates"}
+
+func TestUnaryRequestID(t *testing.T) {
+	var got string
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		got = RequestIDFromContext(ctx)
+		return nil, nil
+	}
+
+	// Идентификатор клиента сохраняется
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(RequestIDHeader, "abc-123"))
+	_, err := unaryRequestID(ctx, nil, testInfo, handler)
+	require.NoError(t, err)
+	require.Equal(t, "abc-123", got)
+
+	// Без идентификатора создается новый
+	_, err = unaryRequestID(context.Background(), nil, testInfo, handler)
+	require.NoError(t, err)
+	require.Len(t, got, 36)
+}
+
+func TestUnaryRecovery(t *testing.T) {
+	_, err := unaryRecovery(context.Background(), nil, testInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		panic("boom")
+	})
+	require.Equal(t, codes.Internal, status.Code(err))
+
+	_, err = unaryRecovery(context.Background(), nil, testInfo, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, errors.New("plain")
+	})
+	require.EqualError(t, err, "plain")
+}
+
+func TestUnaryChain(t *testing.T) {
+	// Паника внутри обработчика проходит через всю цепочку как Internal
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		require.NotEmpty(t, RequestIDFromContext(ctx))
+		panic("boom")
+	}
+	interceptors := UnaryInterceptors(nil)
+	for i := len(interceptors) - 1; i >= 0; i-- {
+		next, interceptor := handler, interceptors[i]
+		handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+			return interceptor(ctx, req, testInfo, next)
+		}
+	}
+
+	_, err := handler(context.Background(), nil)
+	require.Equal(t, codes.Internal, status.Code(err))
+}
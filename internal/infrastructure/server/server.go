@@ -1,12 +1,19 @@
 package server
 
 import (
-	"net"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"rates/internal/auth"
 	"rates/internal/controller"
 	pb "rates/internal/infrastructure/pb"
 	"rates/pkg/logger"
 
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/health/grpc_health_v1"
 )
 
 var (
@@ -14,32 +21,54 @@ var (
 )
 
 type Server struct {
-	controller *controller.Controller
+	controller    *controller.Controller
+	authenticator *auth.Authenticator
+	health        *health.Server
 }
 
-func NewServer(controller *controller.Controller) *Server {
-	return &Server{controller: controller}
+// NewServer создает сервер. authenticator nil отключает проверку API ключей
+func NewServer(controller *controller.Controller, authenticator *auth.Authenticator) *Server {
+	return &Server{controller: controller, authenticator: authenticator, health: health.NewServer()}
 }
 
-func (s *Server) RunApp(host, port string) *grpc.Server {
-	server := grpc.NewServer()
-	pb.RegisterGetRateserServer(server, s.controller)
-
-	addr := net.JoinHostPort(host, port)
+// Health возвращает сервис проверки здоровья, регистрируемый вместе с API
+func (s *Server) Health() *health.Server {
+	return s.health
+}
 
-	listener, err := net.Listen("tcp", addr)
-	if err != nil {
-		log.Fatalf("error to listen: %s", err)
+// GRPCServer создает gRPC сервер с API и проверкой здоровья. tlsConfig nil оставляет соединения без шифрования
+func (s *Server) GRPCServer(tlsConfig *tls.Config) *grpc.Server {
+	opts := []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryInterceptors(s.authenticator)...),
+		grpc.ChainStreamInterceptor(StreamInterceptors(s.authenticator)...),
+		// Трассировка входящих вызовов OpenTelemetry
+		grpc.StatsHandler(otelgrpc.NewServerHandler()),
 	}
+	if tlsConfig != nil {
+		opts = append(opts, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	server := grpc.NewServer(opts...)
+	pb.RegisterGetRateserServer(server, s.controller)
+	grpc_health_v1.RegisterHealthServer(server, s.health)
+	return server
+}
 
-	log.Infof("starting server on %s:%s", host, port)
+// GracefulStop дожидается завершения вызовов и по истечении ctx разрывает оставшиеся соединения
+func GracefulStop(server *grpc.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		done := make(chan struct{})
+		go func() {
+			server.GracefulStop()
+			close(done)
+		}()
 
-	go func() {
-		if err := server.Serve(listener); err != nil {
-			log.Errorf("error to listen server %s", err)
+		select {
+		case <-done:
+			return nil
+		case <-ctx.Done():
+			server.Stop()
+			return fmt.Errorf("graceful stop: %w", ctx.Err())
 		}
-	}()
-
-	return server
+	}
 }
 
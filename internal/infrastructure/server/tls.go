@@ -0,0 +1,205 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// TLSConfig - файлы сертификатов в PEM.
+// На сервере CAFile задает центр сертификации клиентов и включает mTLS,
+// на клиенте - центр сертификации сервера. CertFile и KeyFile клиента нужны только для mTLS
+type TLSConfig struct {
+	CertFile string
+	KeyFile  string
+	CAFile   string
+	// Период проверки файлов на изменение
+	ReloadInterval time.Duration
+}
+
+// CertReloader хранит сертификаты и перечитывает их при изменении файлов без перезапуска сервера
+type CertReloader struct {
+	cfg TLSConfig
+
+	mu      sync.RWMutex
+	cert    *tls.Certificate
+	caPool  *x509.CertPool
+	modTime map[string]time.Time
+}
+
+func NewCertReloader(cfg TLSConfig) (*CertReloader, error) {
+	if (cfg.CertFile == "") != (cfg.KeyFile == "") {
+		return nil, errors.New("tls: certificate and key files must be set together")
+	}
+	r := &CertReloader{cfg: cfg}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *CertReloader) files() []string {
+	var files []string
+	for _, file := range []string{r.cfg.CertFile, r.cfg.KeyFile, r.cfg.CAFile} {
+		if file != "" {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+func (r *CertReloader) load() error {
+	modTime := make(map[string]time.Time, 3)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("tls: %w", err)
+		}
+		modTime[file] = info.ModTime()
+	}
+
+	var cert *tls.Certificate
+	if r.cfg.CertFile != "" {
+		pair, err := tls.LoadX509KeyPair(r.cfg.CertFile, r.cfg.KeyFile)
+		if err != nil {
+			return fmt.Errorf("tls: load key pair: %w", err)
+		}
+		cert = &pair
+	}
+
+	var caPool *x509.CertPool
+	if r.cfg.CAFile != "" {
+		pem, err := os.ReadFile(r.cfg.CAFile)
+		if err != nil {
+			return fmt.Errorf("tls: read CA: %w", err)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("tls: no certificates in %s", r.cfg.CAFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert, r.caPool, r.modTime = cert, caPool, modTime
+	r.mu.Unlock()
+	return nil
+}
+
+// reloadIfChanged перечитывает сертификаты, если изменился хотя бы один файл.
+// При ошибке продолжают действовать прежние сертификаты
+func (r *CertReloader) reloadIfChanged() (bool, error) {
+	r.mu.RLock()
+	changed := false
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil || !info.ModTime().Equal(r.modTime[file]) {
+			changed = true
+			break
+		}
+	}
+	r.mu.RUnlock()
+
+	if !changed {
+		return false, nil
+	}
+	if err := r.load(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Run проверяет файлы каждые ReloadInterval до отмены ctx
+func (r *CertReloader) Run(ctx context.Context) {
+	if r.cfg.ReloadInterval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(r.cfg.ReloadInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			reloaded, err := r.reloadIfChanged()
+			if err != nil {
+				log.Errorf("Failed to reload TLS certificates, keeping previous: %v", err)
+				continue
+			}
+			if reloaded {
+				log.Infof("TLS certificates reloaded from %s", r.cfg.CertFile)
+			}
+		}
+	}
+}
+
+// ServerConfig возвращает настройки TLS сервера. Сертификат и центр сертификации клиентов
+// берутся на каждое подключение, поэтому перезагрузка действует на новые соединения
+func (r *CertReloader) ServerConfig() *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			if r.cert == nil {
+				return nil, errors.New("tls: server certificate is not configured")
+			}
+
+			cfg := &tls.Config{
+				MinVersion:   tls.VersionTLS12,
+				Certificates: []tls.Certificate{*r.cert},
+				// gRPC требует согласования HTTP/2 через ALPN
+				NextProtos: []string{"h2"},
+			}
+			if r.caPool != nil {
+				cfg.ClientCAs = r.caPool
+				cfg.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return cfg, nil
+		},
+	}
+}
+
+// ClientConfig возвращает настройки TLS клиента для подключения к серверу serverName.
+// Клиентский сертификат перечитывается, центр сертификации сервера фиксируется при вызове
+func (r *CertReloader) ClientConfig(serverName string) *tls.Config {
+	r.mu.RLock()
+	caPool := r.caPool
+	r.mu.RUnlock()
+
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		// Без CAFile используются системные центры сертификации
+		RootCAs: caPool,
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			r.mu.RLock()
+			defer r.mu.RUnlock()
+			if r.cert == nil {
+				return &tls.Certificate{}, nil
+			}
+			return r.cert, nil
+		},
+	}
+}
+
+// clientSubject возвращает subject проверенного клиентского сертификата
+func clientSubject(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ""
+	}
+	info, ok := p.AuthInfo.(credentials.TLSInfo)
+	if !ok || len(info.State.VerifiedChains) == 0 || len(info.State.VerifiedChains[0]) == 0 {
+		return ""
+	}
+	return info.State.VerifiedChains[0][0].Subject.String()
+}
@@ -0,0 +1,173 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	pb "rates/internal/infrastructure/pb"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue выпускает сертификат и возвращает его и ключ в PEM
+func (ca *testCA) issue(t *testing.T, cn string, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: cn, Organization: []string{"rates"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+func writeFile(t *testing.T, dir, name string, data []byte) string {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.WriteFile(path, data, 0o600))
+	return path
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := writeFile(t, dir, "ca.pem", ca.pem)
+
+	serverCert, serverKey := ca.issue(t, "localhost", 2, x509.ExtKeyUsageServerAuth)
+	serverTLS, err := NewCertReloader(TLSConfig{
+		CertFile: writeFile(t, dir, "server.pem", serverCert),
+		KeyFile:  writeFile(t, dir, "server.key", serverKey),
+		CAFile:   caFile,
+	})
+	require.NoError(t, err)
+
+	clientCert, clientKey := ca.issue(t, "billing-tool", 3, x509.ExtKeyUsageClientAuth)
+	clientTLS, err := NewCertReloader(TLSConfig{
+		CertFile: writeFile(t, dir, "client.pem", clientCert),
+		KeyFile:  writeFile(t, dir, "client.key", clientKey),
+		CAFile:   caFile,
+	})
+	require.NoError(t, err)
+
+	stub := &stubRateser{}
+	srv := grpc.NewServer(
+		grpc.Creds(credentials.NewTLS(serverTLS.ServerConfig())),
+		grpc.ChainUnaryInterceptor(UnaryInterceptors(nil)...),
+	)
+	pb.RegisterGetRateserServer(srv, stub)
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	go srv.Serve(listener)
+	defer srv.Stop()
+
+	dial := func(cfg *tls.Config) error {
+		conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(credentials.NewTLS(cfg)))
+		require.NoError(t, err)
+		defer conn.Close()
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_, err = pb.NewGetRateserClient(conn).GetRates(ctx, &pb.RatesRequest{Market: "usdtrub"})
+		return err
+	}
+
+	require.NoError(t, dial(clientTLS.ClientConfig("localhost")))
+	require.Equal(t, "CN=billing-tool,O=rates", stub.subject)
+
+	// Без клиентского сертификата сервер отклоняет соединение
+	noCert, err := NewCertReloader(TLSConfig{CAFile: caFile})
+	require.NoError(t, err)
+	require.Error(t, dial(noCert.ClientConfig("localhost")))
+}
+
+func TestCertReloader_Reload(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+
+	cert, key := ca.issue(t, "first", 2, x509.ExtKeyUsageServerAuth)
+	certFile := writeFile(t, dir, "server.pem", cert)
+	keyFile := writeFile(t, dir, "server.key", key)
+	reloader, err := NewCertReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+
+	served := func() string {
+		cfg, err := reloader.ServerConfig().GetConfigForClient(&tls.ClientHelloInfo{})
+		require.NoError(t, err)
+		leaf, err := x509.ParseCertificate(cfg.Certificates[0].Certificate[0])
+		require.NoError(t, err)
+		return leaf.Subject.CommonName
+	}
+	require.Equal(t, "first", served())
+
+	reloaded, err := reloader.reloadIfChanged()
+	require.NoError(t, err)
+	require.False(t, reloaded)
+
+	// Новый сертификат подхватывается после изменения файлов
+	cert, key = ca.issue(t, "second", 3, x509.ExtKeyUsageServerAuth)
+	writeFile(t, dir, "server.pem", cert)
+	writeFile(t, dir, "server.key", key)
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, future, future))
+	require.NoError(t, os.Chtimes(keyFile, future, future))
+
+	reloaded, err = reloader.reloadIfChanged()
+	require.NoError(t, err)
+	require.True(t, reloaded)
+	require.Equal(t, "second", served())
+
+	// Испорченный файл не заменяет действующий сертификат
+	writeFile(t, dir, "server.pem", []byte("broken"))
+	later := future.Add(time.Minute)
+	require.NoError(t, os.Chtimes(certFile, later, later))
+
+	_, err = reloader.reloadIfChanged()
+	require.Error(t, err)
+	require.Equal(t, "second", served())
+}
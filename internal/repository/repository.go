@@ -3,6 +3,7 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"rates/internal/entity"
 	"rates/internal/infrastructure/metrics"
@@ -35,9 +36,15 @@ func NewPostgresClient(dbHost, dbPort, dbUser, dbPassword, dbName string) (*sql.
 	return db, nil
 }
 
+// ErrNotFound возвращается, если в репозитории нет запрошенных данных
+var ErrNotFound = errors.New("not found")
+
 type Repositer interface {
-	InsertAsks(ctx context.Context, dept entity.Depth) error
-	InsertBids(ctx context.Context, dept entity.Depth) error
+	InsertDepth(ctx context.Context, dept entity.Depth) error
+	InsertOrderBook(ctx context.Context, book entity.OrderBook) error
+	LatestDepth(ctx context.Context, market string) (entity.Depth, error)
+	GetHistory(ctx context.Context, filter entity.HistoryFilter) ([]entity.HistoryRecord, error)
+	GetCandles(ctx context.Context, filter entity.CandleFilter) ([]entity.Candle, error)
 }
 
 type Repository struct {
@@ -50,77 +57,293 @@ func NewRepository(db *sql.DB) *Repository {
 	}
 }
 
-func (r *Repository) InsertAsks(ctx context.Context, dept entity.Depth) error {
+// InsertDepth сохраняет заголовок снимка и лучшие ask и bid одной транзакцией.
+// Обе стороны ссылаются на общий snapshot_id, поэтому снимок не может быть записан наполовину
+func (r *Repository) InsertDepth(ctx context.Context, dept entity.Depth) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		metrics.StatusRequestToDB("begin_transaction", "error")
-		log.Errorf("Failed to begin transaction for InsertAsks: %v", err)
+		log.Errorf("Failed to begin transaction for InsertDepth: %v", err)
 		return err
 	}
 	metrics.StatusRequestToDB("begin_transaction", "success")
 
-	log.Infof("Transaction started for InsertAsks")
+	snapshotID, err := insertSnapshot(ctx, tx, dept.Market, dept.Timestamp, &dept)
+	if err != nil {
+		_ = tx.Rollback()
+		metrics.StatusRequestToDB("insert_snapshot", "error")
+		log.Errorf("Failed to insert snapshot: %v", err)
+		return err
+	}
+	metrics.StatusRequestToDB("insert_snapshot", "success")
 
-	err = insertOrder(ctx, tx, dept.Asks, dept.Timestamp, "asks")
+	err = insertOrder(ctx, tx, snapshotID, dept.Market, dept.Source, dept.Asks, dept.Timestamp, "asks")
+	if err == nil {
+		err = insertOrder(ctx, tx, snapshotID, dept.Market, dept.Source, dept.Bids, dept.Timestamp, "bids")
+	}
 	if err != nil {
 		_ = tx.Rollback()
 		metrics.StatusRequestToDB("insert_order", "error")
-		log.Errorf("Failed to insert asks data: %v", err)
+		log.Errorf("Failed to insert depth data: %v", err)
 		return err
 	}
 	metrics.StatusRequestToDB("insert_order", "success")
 
 	if err := tx.Commit(); err != nil {
 		metrics.StatusRequestToDB("commit_transaction", "error")
-		log.Errorf("Failed to commit transaction for InsertAsks: %v", err)
+		log.Errorf("Failed to commit transaction for InsertDepth: %v", err)
 		return err
 	}
 	metrics.StatusRequestToDB("commit_transaction", "success")
-	log.Info("InsertAsks transaction committed successfully")
+	log.Infof("InsertDepth transaction committed successfully, snapshot_id=%d", snapshotID)
 	return nil
 }
 
-func (r *Repository) InsertBids(ctx context.Context, dept entity.Depth) error {
+// InsertOrderBook сохраняет все уровни стакана одной транзакцией
+func (r *Repository) InsertOrderBook(ctx context.Context, book entity.OrderBook) error {
 	tx, err := r.db.Begin()
 	if err != nil {
 		metrics.StatusRequestToDB("begin_transaction", "error")
-		log.Errorf("Failed to begin transaction for InsertBids: %v", err)
+		log.Errorf("Failed to begin transaction for InsertOrderBook: %v", err)
 		return err
 	}
-
 	metrics.StatusRequestToDB("begin_transaction", "success")
 
-	err = insertOrder(ctx, tx, dept.Bids, dept.Timestamp, "bids")
-
+	snapshotID, err := insertSnapshot(ctx, tx, book.Market, book.Timestamp, nil)
 	if err != nil {
 		_ = tx.Rollback()
-		metrics.StatusRequestToDB("insert_order", "error")
-		log.Errorf("Failed to insert bids data: %v", err)
+		metrics.StatusRequestToDB("insert_snapshot", "error")
+		log.Errorf("Failed to insert snapshot: %v", err)
 		return err
 	}
-	metrics.StatusRequestToDB("insert_order", "success")
+	metrics.StatusRequestToDB("insert_snapshot", "success")
+
+	sides := []struct {
+		orders    []entity.Order
+		typeOrder string
+	}{
+		{book.Asks, "asks"},
+		{book.Bids, "bids"},
+	}
+	for _, side := range sides {
+		for level, order := range side.orders {
+			err = insertOrderBookLevel(ctx, tx, snapshotID, book, order, level, side.typeOrder)
+			if err != nil {
+				_ = tx.Rollback()
+				metrics.StatusRequestToDB("insert_order_book", "error")
+				log.Errorf("Failed to insert order book %s: %v", side.typeOrder, err)
+				return err
+			}
+		}
+	}
+	metrics.StatusRequestToDB("insert_order_book", "success")
 
 	if err := tx.Commit(); err != nil {
 		metrics.StatusRequestToDB("commit_transaction", "error")
-		log.Errorf("Failed to commit transaction for InsertBids: %v", err)
+		log.Errorf("Failed to commit transaction for InsertOrderBook: %v", err)
 		return err
 	}
-	metrics.StatusRequestToDB("commit_transaction", "succses")
-	log.Info("InsertBids transaction committed successfully")
+	metrics.StatusRequestToDB("commit_transaction", "success")
+	log.Infof("InsertOrderBook transaction committed successfully: %d asks, %d bids",
+		len(book.Asks), len(book.Bids))
 	return nil
 }
 
-func insertOrder(ctx context.Context, tx *sql.Tx, order entity.Order, timestamp int64, typeOrder string) error {
-	query := `INSERT INTO history (type_price, price, volume, amount, time_stamp_order, transcription_type) 
-	VALUES ($1, $2, $3, $4, $5, $6)`
+func insertOrderBookLevel(ctx context.Context, tx *sql.Tx, snapshotID int64, book entity.OrderBook,
+	order entity.Order, level int, typeOrder string) error {
+	query := `INSERT INTO order_book (market, transcription_type, level, type_price, price, volume, amount,
+		time_stamp_order, snapshot_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+
+	_, err := tx.ExecContext(ctx, query, book.Market, typeOrder, level, order.Type, order.Price,
+		order.Volume, order.Amount, book.Timestamp, snapshotID)
+	return err
+}
+
+// insertSnapshot создает заголовок снимка и возвращает его id.
+// Середина и спред сохраняются только для снимка лучших цен, для стакана они NULL
+func insertSnapshot(ctx context.Context, tx *sql.Tx, market string, timestamp int64, dept *entity.Depth) (int64, error) {
+	query := `INSERT INTO snapshots (market, time_stamp_order, mid, spread, spread_bps)
+	VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	var mid, spread, spreadBps interface{}
+	if dept != nil {
+		mid, spread, spreadBps = dept.Mid, dept.Spread, dept.SpreadBps
+	}
+
+	var id int64
+	err := tx.QueryRowContext(ctx, query, market, timestamp, mid, spread, spreadBps).Scan(&id)
+	return id, err
+}
+
+// LatestDepth возвращает лучшие ask и bid последнего сохраненного снимка рынка вместе с его серединой и спредом.
+// Обе стороны берутся из одного снимка, поэтому цены разных запросов к источнику не смешиваются
+func (r *Repository) LatestDepth(ctx context.Context, market string) (entity.Depth, error) {
+	query := `WITH latest AS (
+		SELECT id, time_stamp_order, mid, spread, spread_bps FROM snapshots
+		WHERE market = $1 AND mid IS NOT NULL
+		ORDER BY time_stamp_order DESC, id DESC
+		LIMIT 1
+	)
+	SELECT h.transcription_type, h.type_price, h.price, h.volume, h.amount, COALESCE(h.source, ''),
+		latest.time_stamp_order, latest.mid, latest.spread, latest.spread_bps
+	FROM latest JOIN history h ON h.snapshot_id = latest.id`
+
+	rows, err := r.db.QueryContext(ctx, query, market)
+	if err != nil {
+		metrics.StatusRequestToDB("select_latest_depth", "error")
+		log.Errorf("Failed to select latest depth: %v", err)
+		return entity.Depth{}, err
+	}
+	defer rows.Close()
+
+	dept := entity.Depth{Market: market}
+	var hasAsks, hasBids bool
+	for rows.Next() {
+		var (
+			typeOrder string
+			order     entity.Order
+		)
+		if err := rows.Scan(&typeOrder, &order.Type, &order.Price, &order.Volume, &order.Amount, &dept.Source,
+			&dept.Timestamp, &dept.Mid, &dept.Spread, &dept.SpreadBps); err != nil {
+			metrics.StatusRequestToDB("select_latest_depth", "error")
+			log.Errorf("Failed to scan latest depth: %v", err)
+			return entity.Depth{}, err
+		}
+		switch typeOrder {
+		case "asks":
+			dept.Asks, hasAsks = order, true
+		case "bids":
+			dept.Bids, hasBids = order, true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		metrics.StatusRequestToDB("select_latest_depth", "error")
+		return entity.Depth{}, err
+	}
+	metrics.StatusRequestToDB("select_latest_depth", "success")
+
+	if !hasAsks || !hasBids {
+		return entity.Depth{}, fmt.Errorf("latest depth for %s: %w", market, ErrNotFound)
+	}
+	return dept, nil
+}
+
+// GetHistory возвращает сохраненные котировки по фильтру в порядке (time_stamp_order, id)
+func (r *Repository) GetHistory(ctx context.Context, filter entity.HistoryFilter) ([]entity.HistoryRecord, error) {
+	query := `SELECT id, market, transcription_type, type_price, price, volume, amount, time_stamp_order,
+		COALESCE(source, '')
+	FROM history
+	WHERE market = $1 AND ($2 = '' OR transcription_type = $2)
+		AND time_stamp_order BETWEEN $3 AND $4
+		AND (time_stamp_order, id) > ($5, $6)
+	ORDER BY time_stamp_order, id
+	LIMIT $7`
+
+	rows, err := r.db.QueryContext(ctx, query, filter.Market, filter.Side, filter.From, filter.To,
+		filter.AfterTimestamp, filter.AfterID, filter.Limit)
+	if err != nil {
+		metrics.StatusRequestToDB("select_history", "error")
+		log.Errorf("Failed to select history: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]entity.HistoryRecord, 0, filter.Limit)
+	for rows.Next() {
+		var rec entity.HistoryRecord
+		if err := rows.Scan(&rec.ID, &rec.Market, &rec.Side, &rec.Order.Type, &rec.Order.Price,
+			&rec.Order.Volume, &rec.Order.Amount, &rec.Timestamp, &rec.Source); err != nil {
+			metrics.StatusRequestToDB("select_history", "error")
+			log.Errorf("Failed to scan history: %v", err)
+			return nil, err
+		}
+		records = append(records, rec)
+	}
+	if err := rows.Err(); err != nil {
+		metrics.StatusRequestToDB("select_history", "error")
+		return nil, err
+	}
+	metrics.StatusRequestToDB("select_history", "success")
+	return records, nil
+}
+
+// GetCandles агрегирует котировки в OHLC свечи средствами Postgres.
+// Интервалы выравниваются по началу эпохи, open/close - первая и последняя котировка интервала.
+// volume - сумма объемов лучшего уровня, ограниченная максимумом DECIMAL(18, 8)
+func (r *Repository) GetCandles(ctx context.Context, filter entity.CandleFilter) ([]entity.Candle, error) {
+	query := `SELECT EXTRACT(EPOCH FROM date_bin(make_interval(secs => $3), to_timestamp(time_stamp_order),
+			TIMESTAMPTZ 'epoch'))::BIGINT AS bucket,
+		(array_agg(price ORDER BY time_stamp_order, id))[1] AS open,
+		MAX(price) AS high,
+		MIN(price) AS low,
+		(array_agg(price ORDER BY time_stamp_order DESC, id DESC))[1] AS close,
+		LEAST(SUM(volume), 9999999999.99999999) AS volume,
+		COUNT(*) AS count
+	FROM history
+	WHERE market = $1 AND transcription_type = $2 AND time_stamp_order BETWEEN $4 AND $5
+	GROUP BY bucket
+	ORDER BY bucket`
+
+	rows, err := r.db.QueryContext(ctx, query, filter.Market, filter.Side, filter.Interval, filter.From, filter.To)
+	if err != nil {
+		metrics.StatusRequestToDB("select_candles", "error")
+		log.Errorf("Failed to select candles: %v", err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	var candles []entity.Candle
+	for rows.Next() {
+		var c entity.Candle
+		if err := rows.Scan(&c.Timestamp, &c.Open, &c.High, &c.Low, &c.Close, &c.Volume, &c.Count); err != nil {
+			metrics.StatusRequestToDB("select_candles", "error")
+			log.Errorf("Failed to scan candle: %v", err)
+			return nil, err
+		}
+		candles = append(candles, c)
+	}
+	if err := rows.Err(); err != nil {
+		metrics.StatusRequestToDB("select_candles", "error")
+		return nil, err
+	}
+	metrics.StatusRequestToDB("select_candles", "success")
+	return candles, nil
+}
+
+func insertOrder(ctx context.Context, tx *sql.Tx, snapshotID int64, market, source string, order entity.Order,
+	timestamp int64, typeOrder string) error {
+	query := `INSERT INTO history (type_price, price, volume, amount, time_stamp_order, transcription_type, market,
+		snapshot_id, source) 
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
 
 	_, err := tx.ExecContext(ctx, query, order.Type, order.Price, order.Volume,
-		order.Amount, timestamp, typeOrder)
+		order.Amount, timestamp, typeOrder, market, snapshotID, source)
 
 	if err != nil {
 		log.Errorf("failed to insert order data: %v", err)
 		return err
 	}
-	log.Infof("Successfully inserted order data for type=%s", typeOrder)
+	log.Infof("Successfully inserted order data for market=%s type=%s", market, typeOrder)
 	return nil
 }
+
+// APIKeyByHash возвращает действующий API ключ по sha256 хэшу
+func (r *Repository) APIKeyByHash(ctx context.Context, keyHash string) (entity.APIKey, error) {
+	query := `SELECT client_id, COALESCE(rate_limit, 0), COALESCE(burst, 0)
+	FROM api_keys WHERE key_hash = $1 AND revoked_at IS NULL`
+
+	var key entity.APIKey
+	err := r.db.QueryRowContext(ctx, query, keyHash).Scan(&key.ClientID, &key.RateLimit, &key.Burst)
+	if errors.Is(err, sql.ErrNoRows) {
+		metrics.StatusRequestToDB("select_api_key", "success")
+		return entity.APIKey{}, fmt.Errorf("api key: %w", ErrNotFound)
+	}
+	if err != nil {
+		metrics.StatusRequestToDB("select_api_key", "error")
+		log.Errorf("Failed to select api key: %v", err)
+		return entity.APIKey{}, err
+	}
+	metrics.StatusRequestToDB("select_api_key", "success")
+	return key, nil
+}
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"rates/internal/entity"
 	"testing"
 	"time"
@@ -26,20 +27,21 @@ func TestInsertOrder(t *testing.T) {
 	ctx := context.Background()
 	order := entity.Order{
 		Type:   "limit",
-		Price:  "100.50",
-		Volume: "2",
-		Amount: "201.0",
+		Price:  entity.MustDecimal("100.50"),
+		Volume: entity.MustDecimal("2"),
+		Amount: entity.MustDecimal("201.0"),
 	}
 	timestamp := time.Now().Unix()
 	typeOrder := "buy"
+	market := "usdtrub"
 
 	// Ожидаем вызов SQL-запроса на вставку
-	mock.ExpectExec(`INSERT INTO history \(type_price, price, volume, amount, time_stamp_order, transcription_type\)`).
-		WithArgs(order.Type, order.Price, order.Volume, order.Amount, timestamp, typeOrder).
+	mock.ExpectExec(`INSERT INTO history \(type_price, price, volume, amount, time_stamp_order, transcription_type, market,\s+snapshot_id, source\)`).
+		WithArgs(order.Type, order.Price, order.Volume, order.Amount, timestamp, typeOrder, market, int64(1), "garantex").
 		WillReturnResult(sqlmock.NewResult(1, 1)) // Успешный результат
 
 	// Вызываем тестируемую функцию
-	err = insertOrder(ctx, mockTx, order, timestamp, typeOrder)
+	err = insertOrder(ctx, mockTx, 1, market, "garantex", order, timestamp, typeOrder)
 	require.NoError(t, err)
 
 	// Ожидаем завершения транзакции (Commit)
@@ -52,3 +54,214 @@ func TestInsertOrder(t *testing.T) {
 	// Убеждаемся, что все ожидания выполнены
 	require.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestInsertDepth(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	dept := entity.Depth{
+		Market:    "usdtrub",
+		Timestamp: 1234567890,
+		Asks:      entity.Order{Type: "limit", Price: entity.MustDecimal("100"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("100")},
+		Bids:      entity.Order{Type: "limit", Price: entity.MustDecimal("90"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("90")},
+		Mid:       entity.MustDecimal("95"),
+		Spread:    entity.MustDecimal("10"),
+		SpreadBps: entity.MustDecimal("1052.63"),
+		Source:    "garantex",
+	}
+
+	// Заголовок снимка и обе стороны пишутся в одной транзакции с общим snapshot_id
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO snapshots`).
+		WithArgs("usdtrub", int64(1234567890), "95", "10", "1052.63").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectExec(`INSERT INTO history`).
+		WithArgs("limit", "100", "1", "100", int64(1234567890), "asks", "usdtrub", int64(7), "garantex").
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO history`).
+		WithArgs("limit", "90", "1", "90", int64(1234567890), "bids", "usdtrub", int64(7), "garantex").
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectCommit()
+
+	require.NoError(t, repo.InsertDepth(context.Background(), dept))
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertDepth_Rollback(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	// Ошибка записи bids откатывает уже записанные asks
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO snapshots`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(7)))
+	mock.ExpectExec(`INSERT INTO history`).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO history`).WillReturnError(errors.New("db error"))
+	mock.ExpectRollback()
+
+	err = repo.InsertDepth(context.Background(), entity.Depth{Market: "usdtrub"})
+	require.Error(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestInsertOrderBook(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	book := entity.OrderBook{
+		Market:    "usdtrub",
+		Timestamp: 1234567890,
+		Asks: []entity.Order{
+			{Type: "limit", Price: entity.MustDecimal("100"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("100")},
+			{Type: "limit", Price: entity.MustDecimal("101"), Volume: entity.MustDecimal("2"), Amount: entity.MustDecimal("202")},
+		},
+		Bids: []entity.Order{
+			{Type: "limit", Price: entity.MustDecimal("90"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("90")},
+		},
+	}
+
+	// Все уровни стакана пишутся в одной транзакции под общим заголовком снимка
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO snapshots`).
+		WithArgs("usdtrub", int64(1234567890), nil, nil, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(5)))
+	mock.ExpectExec(`INSERT INTO order_book`).
+		WithArgs("usdtrub", "asks", 0, "limit", "100", "1", "100", int64(1234567890), int64(5)).
+		WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec(`INSERT INTO order_book`).
+		WithArgs("usdtrub", "asks", 1, "limit", "101", "2", "202", int64(1234567890), int64(5)).
+		WillReturnResult(sqlmock.NewResult(2, 1))
+	mock.ExpectExec(`INSERT INTO order_book`).
+		WithArgs("usdtrub", "bids", 0, "limit", "90", "1", "90", int64(1234567890), int64(5)).
+		WillReturnResult(sqlmock.NewResult(3, 1))
+	mock.ExpectCommit()
+
+	err = repo.InsertOrderBook(context.Background(), book)
+	require.NoError(t, err)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLatestDepth(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	columns := []string{"transcription_type", "type_price", "price", "volume", "amount", "source",
+		"time_stamp_order", "mid", "spread", "spread_bps"}
+	rows := sqlmock.NewRows(columns).
+		AddRow("asks", "limit", "100.00000000", "1.00000000", "100.00000000", "garantex", int64(1234567890),
+			"95.00000000", "10.00000000", "1052.63157895").
+		AddRow("bids", "limit", "90.00000000", "2.00000000", "180.00000000", "garantex", int64(1234567890),
+			"95.00000000", "10.00000000", "1052.63157895")
+	mock.ExpectQuery(`FROM latest JOIN history h ON h.snapshot_id = latest.id`).WithArgs("usdtrub").WillReturnRows(rows)
+
+	// Обе стороны и расчеты берутся из одного последнего снимка
+	dept, err := repo.LatestDepth(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	require.Equal(t, "usdtrub", dept.Market)
+	require.Equal(t, "100", dept.Asks.Price.String())
+	require.Equal(t, "90", dept.Bids.Price.String())
+	require.Equal(t, int64(1234567890), dept.Timestamp)
+	require.Equal(t, "garantex", dept.Source)
+	require.Equal(t, "95", dept.Mid.String())
+	require.Equal(t, "10", dept.Spread.String())
+	require.Equal(t, "1052.63157895", dept.SpreadBps.String())
+
+	// Нет сохраненных котировок по рынку
+	mock.ExpectQuery(`FROM latest JOIN history`).WithArgs("btcrub").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	_, err = repo.LatestDepth(context.Background(), "btcrub")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetHistory(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	filter := entity.HistoryFilter{
+		Market:         "usdtrub",
+		Side:           "asks",
+		From:           100,
+		To:             200,
+		AfterTimestamp: 150,
+		AfterID:        7,
+		Limit:          2,
+	}
+	rows := sqlmock.NewRows([]string{"id", "market", "transcription_type", "type_price", "price", "volume", "amount", "time_stamp_order", "source"}).
+		AddRow(int64(8), "usdtrub", "asks", "limit", "100.5", "1", "100.5", int64(150), "garantex").
+		AddRow(int64(9), "usdtrub", "asks", "limit", "101", "2", "202", int64(160), "")
+	mock.ExpectQuery(`SELECT id, market, transcription_type`).
+		WithArgs("usdtrub", "asks", int64(100), int64(200), int64(150), int64(7), 2).
+		WillReturnRows(rows)
+
+	records, err := repo.GetHistory(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	require.Equal(t, int64(8), records[0].ID)
+	require.Equal(t, "100.5", records[0].Order.Price.String())
+	require.Equal(t, int64(160), records[1].Timestamp)
+	require.Equal(t, "garantex", records[0].Source)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestGetCandles(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	filter := entity.CandleFilter{Market: "usdtrub", Side: "bids", Interval: 60, From: 0, To: 3600}
+	rows := sqlmock.NewRows([]string{"bucket", "open", "high", "low", "close", "volume", "count"}).
+		AddRow(int64(0), "90", "92", "89", "91", "10.5", int64(4)).
+		AddRow(int64(60), "91", "91", "90", "90", "3", int64(2))
+	mock.ExpectQuery(`date_bin`).
+		WithArgs("usdtrub", "bids", int64(60), int64(0), int64(3600)).
+		WillReturnRows(rows)
+
+	candles, err := repo.GetCandles(context.Background(), filter)
+	require.NoError(t, err)
+	require.Len(t, candles, 2)
+	require.Equal(t, entity.Candle{Timestamp: 0, Open: entity.MustDecimal("90"), High: entity.MustDecimal("92"), Low: entity.MustDecimal("89"), Close: entity.MustDecimal("91"), Volume: entity.MustDecimal("10.5"), Count: 4}, candles[0])
+	require.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyByHash(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	defer db.Close()
+
+	repo := NewRepository(db)
+
+	mock.ExpectQuery(`SELECT client_id, COALESCE\(rate_limit, 0\), COALESCE\(burst, 0\)\s+FROM api_keys`).
+		WithArgs("hash").
+		WillReturnRows(sqlmock.NewRows([]string{"client_id", "rate_limit", "burst"}).AddRow("billing", 5.0, 10))
+
+	key, err := repo.APIKeyByHash(context.Background(), "hash")
+	require.NoError(t, err)
+	require.Equal(t, entity.APIKey{ClientID: "billing", RateLimit: 5, Burst: 10}, key)
+
+	// Неизвестный или отозванный ключ
+	mock.ExpectQuery(`FROM api_keys`).WithArgs("unknown").
+		WillReturnRows(sqlmock.NewRows([]string{"client_id", "rate_limit", "burst"}))
+
+	_, err = repo.APIKeyByHash(context.Background(), "unknown")
+	require.ErrorIs(t, err, ErrNotFound)
+	require.NoError(t, mock.ExpectationsWereMet())
+}
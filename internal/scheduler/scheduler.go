@@ -0,0 +1,213 @@
+package scheduler
+
+import (
+	"context"
+	"math/rand"
+	"rates/internal/entity"
+	"rates/internal/infrastructure/metrics"
+	"rates/pkg/logger"
+	"sync"
+	"time"
+)
+
+var (
+	log = logger.Logger().Named("scheduler").Sugar()
+)
+
+// Collector запрашивает котировки по рынку и сохраняет их
+type Collector interface {
+	Collect(ctx context.Context, market string) (entity.Depth, error)
+}
+
+// Job - периодический опрос одного рынка
+type Job struct {
+	Market   string
+	Interval time.Duration
+}
+
+// Scheduler опрашивает рынки по расписанию независимо от запросов клиентов
+type Scheduler struct {
+	collector Collector
+	jitter    time.Duration
+
+	mu      sync.Mutex
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped bool
+	jobs    []Job
+	running map[string]runningJob
+	wg      sync.WaitGroup
+}
+
+// runningJob - запущенный опрос рынка
+type runningJob struct {
+	interval time.Duration
+	cancel   context.CancelFunc
+}
+
+func New(collector Collector, jobs []Job, jitter time.Duration) *Scheduler {
+	return &Scheduler{collector: collector, jobs: jobs, jitter: jitter, running: make(map[string]runningJob)}
+}
+
+// Start запускает по горутине на каждый рынок. Первый опрос выполняется со случайной задержкой до jitter
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	s.ctx, s.cancel = context.WithCancel(ctx)
+
+	for _, job := range s.jobs {
+		s.startJob(job)
+	}
+	log.Infof("Scheduler started for %d markets", len(s.running))
+}
+
+// Update заменяет список рынков. Опрос новых рынков и рынков с измененным интервалом запускается заново,
+// опрос рынков, которых нет в списке, останавливается. До Start список только запоминается
+func (s *Scheduler) Update(jobs []Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = jobs
+	if s.ctx == nil {
+		return
+	}
+
+	wanted := make(map[string]time.Duration, len(jobs))
+	for _, job := range jobs {
+		wanted[job.Market] = job.Interval
+	}
+	for market, running := range s.running {
+		if interval, ok := wanted[market]; !ok || interval != running.interval {
+			running.cancel()
+			delete(s.running, market)
+		}
+	}
+	for _, job := range jobs {
+		if _, ok := s.running[job.Market]; !ok {
+			s.startJob(job)
+		}
+	}
+	log.Infof("Scheduler updated for %d markets", len(s.running))
+}
+
+// Add запускает опрос рынка, если он еще не опрашивается. До Start рынок только добавляется в список,
+// после Stop не добавляется
+func (s *Scheduler) Add(job Job) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		return
+	}
+	for _, existing := range s.jobs {
+		if existing.Market == job.Market {
+			return
+		}
+	}
+	s.jobs = append(s.jobs, job)
+	if s.ctx != nil {
+		s.startJob(job)
+	}
+}
+
+// Remove останавливает опрос рынка. Текущий запрос по рынку не дожидается
+func (s *Scheduler) Remove(market string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	jobs := make([]Job, 0, len(s.jobs))
+	for _, job := range s.jobs {
+		if job.Market != market {
+			jobs = append(jobs, job)
+		}
+	}
+	s.jobs = jobs
+	if running, ok := s.running[market]; ok {
+		running.cancel()
+		delete(s.running, market)
+	}
+}
+
+// startJob вызывается под s.mu
+func (s *Scheduler) startJob(job Job) {
+	if job.Interval <= 0 {
+		log.Warnf("Skip polling %s: non-positive interval %s", job.Market, job.Interval)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(s.ctx)
+	s.running[job.Market] = runningJob{interval: job.Interval, cancel: cancel}
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.run(ctx, job)
+	}()
+}
+
+// Stop останавливает опрос и дожидается завершения текущих запросов. Повторно планировщик не запускается
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.ctx, s.cancel = nil, nil
+	s.stopped = true
+	s.running = make(map[string]runningJob)
+	s.mu.Unlock()
+	s.wg.Wait()
+	log.Info("Scheduler stopped")
+}
+
+func (s *Scheduler) run(ctx context.Context, job Job) {
+	// Первый опрос тоже сдвигается, иначе после запуска все рынки запрашиваются одновременно
+	if !s.wait(ctx, s.next(0)) {
+		return
+	}
+	for {
+		s.poll(ctx, job.Market)
+		if !s.wait(ctx, s.next(job.Interval)) {
+			return
+		}
+	}
+}
+
+// wait ждет delay и возвращает false, если опрос остановлен раньше
+func (s *Scheduler) wait(ctx context.Context, delay time.Duration) bool {
+	if delay <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+func (s *Scheduler) poll(ctx context.Context, market string) {
+	start := time.Now()
+
+	_, err := s.collector.Collect(ctx, market)
+	if err != nil {
+		// Ошибка из-за остановки планировщика не считается сбоем опроса
+		if ctx.Err() != nil {
+			return
+		}
+		metrics.StatusPoll(market, "error")
+		log.Errorf("Failed to poll %s: %v", market, err)
+		return
+	}
+	metrics.StatusPoll(market, "success")
+	log.Debugf("Polled %s in %s", market, time.Since(start))
+}
+
+// next возвращает интервал до следующего опроса со случайным сдвигом,
+// чтобы запросы по разным рынкам не уходили к бирже одновременно
+func (s *Scheduler) next(interval time.Duration) time.Duration {
+	if s.jitter <= 0 {
+		return interval
+	}
+	return interval + time.Duration(rand.Int63n(int64(s.jitter)))
+}
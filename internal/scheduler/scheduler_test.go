@@ -0,0 +1,122 @@
+package scheduler
+
+import (
+	"context"
+	"rates/internal/entity"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type countingCollector struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (c *countingCollector) Collect(_ context.Context, market string) (entity.Depth, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls[market]++
+	return entity.Depth{Market: market}, nil
+}
+
+func (c *countingCollector) count(market string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls[market]
+}
+
+func TestScheduler(t *testing.T) {
+	collector := &countingCollector{calls: make(map[string]int)}
+
+	s := New(collector, []Job{
+		{Market: "usdtrub", Interval: 10 * time.Millisecond},
+		{Market: "btcrub", Interval: time.Hour},
+	}, time.Millisecond)
+	s.Start(context.Background())
+
+	// Частый рынок опрашивается многократно, редкий - только при старте
+	require.Eventually(t, func() bool {
+		return collector.count("usdtrub") >= 3
+	}, time.Second, 5*time.Millisecond)
+	require.Equal(t, 1, collector.count("btcrub"))
+
+	s.Stop()
+
+	// После остановки опросов больше нет
+	stopped := collector.count("usdtrub")
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, stopped, collector.count("usdtrub"))
+}
+
+func TestSchedulerUpdate(t *testing.T) {
+	collector := &countingCollector{calls: make(map[string]int)}
+
+	s := New(collector, []Job{
+		{Market: "usdtrub", Interval: 10 * time.Millisecond},
+		{Market: "btcrub", Interval: time.Hour},
+	}, 0)
+	s.Start(context.Background())
+	defer s.Stop()
+	require.Eventually(t, func() bool {
+		return collector.count("usdtrub") >= 1 && collector.count("btcrub") == 1
+	}, time.Second, 5*time.Millisecond)
+
+	// usdtrub убран, btcrub опрашивается чаще, ethusdt добавлен
+	s.Update([]Job{
+		{Market: "btcrub", Interval: 10 * time.Millisecond},
+		{Market: "ethusdt", Interval: 10 * time.Millisecond},
+	})
+	removed := collector.count("usdtrub")
+	require.Eventually(t, func() bool {
+		return collector.count("btcrub") >= 3 && collector.count("ethusdt") >= 3
+	}, time.Second, 5*time.Millisecond)
+	require.LessOrEqual(t, collector.count("usdtrub"), removed+1)
+}
+
+func TestSchedulerAddRemove(t *testing.T) {
+	collector := &countingCollector{calls: make(map[string]int)}
+
+	s := New(collector, nil, 0)
+	s.Start(context.Background())
+	defer s.Stop()
+
+	// Добавленный рынок опрашивается сразу, повторное добавление не запускает второй опрос
+	s.Add(Job{Market: "usdtrub", Interval: time.Hour})
+	s.Add(Job{Market: "usdtrub", Interval: time.Hour})
+	require.Eventually(t, func() bool {
+		return collector.count("usdtrub") == 1
+	}, time.Second, 5*time.Millisecond)
+
+	s.Add(Job{Market: "btcrub", Interval: 10 * time.Millisecond})
+	require.Eventually(t, func() bool {
+		return collector.count("btcrub") >= 2
+	}, time.Second, 5*time.Millisecond)
+
+	s.Remove("btcrub")
+	time.Sleep(20 * time.Millisecond)
+	removed := collector.count("btcrub")
+	time.Sleep(30 * time.Millisecond)
+	require.Equal(t, removed, collector.count("btcrub"))
+	require.Equal(t, 1, collector.count("usdtrub"))
+}
+
+func TestSchedulerStopAndJitter(t *testing.T) {
+	collector := &countingCollector{calls: make(map[string]int)}
+
+	// Первый опрос сдвигается на случайную задержку до jitter
+	s := New(collector, []Job{{Market: "usdtrub", Interval: time.Hour}}, time.Hour)
+	s.Start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, 0, collector.count("usdtrub"))
+	s.Stop()
+
+	// После остановки рынки не добавляются и планировщик не запускается заново
+	s.Add(Job{Market: "btcrub", Interval: time.Millisecond})
+	s.Start(context.Background())
+	time.Sleep(20 * time.Millisecond)
+	require.Equal(t, 0, collector.count("btcrub"))
+	require.Equal(t, 0, collector.count("usdtrub"))
+}
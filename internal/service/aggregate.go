@@ -0,0 +1,243 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rates/internal/entity"
+	"rates/internal/source"
+	"sort"
+	"strings"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+)
+
+// Способы расчета сводного курса
+const (
+	AggregateMedian   = "median"
+	AggregateWeighted = "weighted"
+)
+
+// DefaultOutlierBps - допустимое отклонение источника от медианы, если оно не задано в конфигурации
+const DefaultOutlierBps = 200
+
+// minSourcesForOutliers - при меньшем числе источников нельзя определить, какой из них выброс
+const minSourcesForOutliers = 3
+
+// ErrNoConsensus возвращается, если ни один источник не дал пригодной котировки
+var ErrNoConsensus = errors.New("no source quotes for consensus")
+
+// GetAggregatedRates параллельно запрашивает рынок у всех источников сводного курса,
+// отбрасывает ошибки, устаревшие котировки и выбросы и считает по оставшимся сводные цены
+func (s Service) GetAggregatedRates(ctx context.Context, query entity.AggregateQuery) (entity.AggregatedRate, error) {
+	log.Debug("Starting GetAggregatedRates request")
+
+	market, err := s.resolveMarket(query.Market)
+	if err != nil {
+		return entity.AggregatedRate{}, err
+	}
+	method, err := normalizeAggregateMethod(query.Method)
+	if err != nil {
+		return entity.AggregatedRate{}, err
+	}
+
+	tracer := otel.Tracer("service.GetAggregatedRates")
+	ctx, span := tracer.Start(ctx, "Service")
+	defer span.End()
+
+	quotes := s.fetchQuotes(ctx, market)
+	s.markStale(quotes)
+	if err := s.markOutliers(quotes); err != nil {
+		return entity.AggregatedRate{}, err
+	}
+
+	accepted := make([]entity.SourceQuote, 0, len(quotes))
+	for _, quote := range quotes {
+		if quote.Status == entity.QuoteAccepted {
+			accepted = append(accepted, quote)
+		}
+	}
+	if len(accepted) == 0 {
+		return entity.AggregatedRate{}, fmt.Errorf("%w: market %s", ErrNoConsensus, market)
+	}
+
+	rate, err := consensus(accepted, method)
+	if err != nil {
+		return entity.AggregatedRate{}, err
+	}
+	rate.Market = market
+	rate.Method = method
+	rate.Quotes = quotes
+	return rate, nil
+}
+
+// fetchQuotes запрашивает лучшие цены у всех источников одновременно. Порядок ответов совпадает с порядком источников
+func (s Service) fetchQuotes(ctx context.Context, market string) []entity.SourceQuote {
+	quotes := make([]entity.SourceQuote, len(s.aggSources))
+
+	var wg sync.WaitGroup
+	for i, src := range s.aggSources {
+		wg.Add(1)
+		go func(i int, src source.RateSource) {
+			defer wg.Done()
+			quotes[i] = fetchQuote(ctx, src, market)
+		}(i, src)
+	}
+	wg.Wait()
+	return quotes
+}
+
+func fetchQuote(ctx context.Context, src source.RateSource, market string) entity.SourceQuote {
+	quote := entity.SourceQuote{Source: src.Name()}
+
+	data, err := fetchDepthFrom(ctx, src, market)
+	if err == nil {
+		err = data.CheckBook()
+	}
+	if err != nil {
+		quote.Status = entity.QuoteFailed
+		quote.Error = err.Error()
+		return quote
+	}
+
+	dept, err := withSpread(entity.Depth{Asks: data.Asks[0], Bids: data.Bids[0]})
+	if err != nil {
+		quote.Status = entity.QuoteFailed
+		quote.Error = err.Error()
+		return quote
+	}
+	quote.Status = entity.QuoteAccepted
+	quote.Timestamp = data.Timestamp
+	quote.Asks = dept.Asks
+	quote.Bids = dept.Bids
+	quote.Mid = dept.Mid
+	return quote
+}
+
+// markStale отбрасывает котировки старше aggMaxAge
+func (s Service) markStale(quotes []entity.SourceQuote) {
+	if s.aggMaxAge <= 0 {
+		return
+	}
+	oldest := s.now().Add(-s.aggMaxAge).Unix()
+	for i := range quotes {
+		if quotes[i].Status == entity.QuoteAccepted && quotes[i].Timestamp < oldest {
+			quotes[i].Status = entity.QuoteStale
+		}
+	}
+}
+
+// markOutliers отбрасывает котировки, середина которых отклоняется от медианы больше чем на outlierBps
+func (s Service) markOutliers(quotes []entity.SourceQuote) error {
+	mids := make([]entity.Decimal, 0, len(quotes))
+	for _, quote := range quotes {
+		if quote.Status == entity.QuoteAccepted {
+			mids = append(mids, quote.Mid)
+		}
+	}
+	if len(mids) < minSourcesForOutliers {
+		return nil
+	}
+
+	median, err := medianOf(mids)
+	if err != nil {
+		return err
+	}
+	for i := range quotes {
+		if quotes[i].Status != entity.QuoteAccepted {
+			continue
+		}
+		deviation, err := quotes[i].Mid.Sub(median).Abs().Quo(median)
+		if err != nil {
+			return err
+		}
+		deviation, err = deviation.Mul(basisPoints)
+		if err != nil {
+			return err
+		}
+		if deviation.Cmp(s.outlierBps) > 0 {
+			quotes[i].Status = entity.QuoteOutlier
+		}
+	}
+	return nil
+}
+
+// consensus считает сводные ask и bid по принятым котировкам
+func consensus(quotes []entity.SourceQuote, method string) (entity.AggregatedRate, error) {
+	asks := make([]entity.Order, 0, len(quotes))
+	bids := make([]entity.Order, 0, len(quotes))
+	var rate entity.AggregatedRate
+	for _, quote := range quotes {
+		asks = append(asks, quote.Asks)
+		bids = append(bids, quote.Bids)
+		rate.Timestamp = max(rate.Timestamp, quote.Timestamp)
+	}
+
+	price := medianPrice
+	if method == AggregateWeighted {
+		price = weightedPrice
+	}
+	ask, err := price(asks)
+	if err != nil {
+		return entity.AggregatedRate{}, err
+	}
+	bid, err := price(bids)
+	if err != nil {
+		return entity.AggregatedRate{}, err
+	}
+
+	dept, err := withSpread(entity.Depth{Asks: entity.Order{Price: ask}, Bids: entity.Order{Price: bid}})
+	if err != nil {
+		return entity.AggregatedRate{}, err
+	}
+	rate.Ask = ask
+	rate.Bid = bid
+	rate.Mid = dept.Mid
+	return rate, nil
+}
+
+func medianPrice(orders []entity.Order) (entity.Decimal, error) {
+	prices := make([]entity.Decimal, 0, len(orders))
+	for _, order := range orders {
+		prices = append(prices, order.Price)
+	}
+	return medianOf(prices)
+}
+
+// weightedPrice - средняя цена, взвешенная по объему лучшего уровня каждого источника
+func weightedPrice(orders []entity.Order) (entity.Decimal, error) {
+	var total, volume entity.Decimal
+	for _, order := range orders {
+		cost, err := order.Price.Mul(order.Volume)
+		if err != nil {
+			return entity.Decimal{}, err
+		}
+		total = total.Add(cost)
+		volume = volume.Add(order.Volume)
+	}
+	return total.Quo(volume)
+}
+
+// medianOf возвращает медиану. Для четного количества - среднее двух центральных значений
+func medianOf(values []entity.Decimal) (entity.Decimal, error) {
+	sorted := append([]entity.Decimal(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Cmp(sorted[j]) < 0 })
+
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2], nil
+	}
+	return sorted[n/2-1].Add(sorted[n/2]).Quo(two)
+}
+
+func normalizeAggregateMethod(method string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(method)) {
+	case "", AggregateMedian:
+		return AggregateMedian, nil
+	case AggregateWeighted, "vwap":
+		return AggregateWeighted, nil
+	default:
+		return "", fmt.Errorf("%w: unknown aggregation method %q", ErrInvalidArgument, method)
+	}
+}
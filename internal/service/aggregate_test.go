@@ -0,0 +1,86 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rates/internal/entity"
+	"rates/internal/source"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func quoteSource(name string, ask, bid, volume string, timestamp int64) *MockRateSource {
+	src := &MockRateSource{name: name}
+	src.On("GetDepth", mock.Anything, "usdtrub").Return(entity.DepthRequest{
+		Timestamp: timestamp,
+		Asks:      []entity.Order{{Price: entity.MustDecimal(ask), Volume: entity.MustDecimal(volume)}},
+		Bids:      []entity.Order{{Price: entity.MustDecimal(bid), Volume: entity.MustDecimal(volume)}},
+	}, nil)
+	return src
+}
+
+func TestGetAggregatedRates(t *testing.T) {
+	now := time.Unix(1000, 0)
+	failed := &MockRateSource{name: "down"}
+	failed.On("GetDepth", mock.Anything, "usdtrub").Return(entity.DepthRequest{}, errors.New("timeout"))
+
+	sources := []source.RateSource{
+		quoteSource("a", "101", "99", "1", 1000),
+		quoteSource("b", "103", "101", "3", 995),
+		quoteSource("c", "102", "100", "1", 1000),
+		// Середина 150 отклоняется от медианы больше чем на 2%
+		quoteSource("outlier", "151", "149", "100", 1000),
+		// Котировка старше минуты
+		quoteSource("stale", "100", "98", "1", 900),
+		failed,
+	}
+	service := NewService(new(MockRepositer), new(MockRateSource), Config{
+		AggregateSources: sources,
+		AggregateMaxAge:  time.Minute,
+	})
+	service.now = func() time.Time { return now }
+
+	rate, err := service.GetAggregatedRates(context.Background(), entity.AggregateQuery{Market: "usdtrub"})
+	assert.NoError(t, err)
+	assert.Equal(t, AggregateMedian, rate.Method)
+	assert.Equal(t, "102", rate.Ask.String())
+	assert.Equal(t, "100", rate.Bid.String())
+	assert.Equal(t, "101", rate.Mid.String())
+	assert.Equal(t, int64(1000), rate.Timestamp)
+
+	statuses := make(map[string]string, len(rate.Quotes))
+	for _, quote := range rate.Quotes {
+		statuses[quote.Source] = quote.Status
+	}
+	assert.Equal(t, map[string]string{
+		"a":       entity.QuoteAccepted,
+		"b":       entity.QuoteAccepted,
+		"c":       entity.QuoteAccepted,
+		"outlier": entity.QuoteOutlier,
+		"stale":   entity.QuoteStale,
+		"down":    entity.QuoteFailed,
+	}, statuses)
+
+	// Взвешенный по объему курс: (101 + 103*3 + 102) / 5
+	rate, err = service.GetAggregatedRates(context.Background(), entity.AggregateQuery{Market: "usdtrub", Method: "weighted"})
+	assert.NoError(t, err)
+	assert.Equal(t, "102.4", rate.Ask.String())
+	assert.Equal(t, "100.4", rate.Bid.String())
+}
+
+func TestGetAggregatedRates_NoConsensus(t *testing.T) {
+	failed := &MockRateSource{name: "down"}
+	failed.On("GetDepth", mock.Anything, "usdtrub").Return(entity.DepthRequest{}, errors.New("timeout"))
+
+	service := NewService(new(MockRepositer), failed, Config{})
+
+	_, err := service.GetAggregatedRates(context.Background(), entity.AggregateQuery{})
+	assert.ErrorIs(t, err, ErrNoConsensus)
+
+	_, err = service.GetAggregatedRates(context.Background(), entity.AggregateQuery{Method: "mode"})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
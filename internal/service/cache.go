@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"rates/internal/entity"
+	"rates/internal/infrastructure/metrics"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+type cacheEntry struct {
+	dept      entity.Depth
+	fetchedAt time.Time
+}
+
+// CachedService отдает последние котировки по рынку из памяти, пока они моложе maxAge.
+// Одновременные промахи по одному рынку схлопываются в один запрос к Service
+type CachedService struct {
+	*Service
+
+	maxAge time.Duration
+	group  singleflight.Group
+
+	mu      sync.RWMutex
+	entries map[string]cacheEntry
+	now     func() time.Time
+}
+
+func NewCachedService(service *Service, maxAge time.Duration) *CachedService {
+	return &CachedService{
+		Service: service,
+		maxAge:  maxAge,
+		entries: make(map[string]cacheEntry),
+		now:     time.Now,
+	}
+}
+
+func (c *CachedService) GetRates(ctx context.Context, market string) (entity.Depth, error) {
+	market, err := c.resolveMarket(market)
+	if err != nil {
+		return entity.Depth{}, err
+	}
+
+	if dept, ok := c.lookup(market); ok {
+		metrics.CacheHit(market)
+		return dept, nil
+	}
+	metrics.CacheMiss(market)
+
+	// Запрос выполняется без отмены контекста первого клиента,
+	// иначе его отключение вернет ошибку всем ожидающим
+	v, err, _ := c.group.Do(market, func() (interface{}, error) {
+		if dept, ok := c.lookup(market); ok {
+			return dept, nil
+		}
+		dept, err := c.Service.GetRates(context.WithoutCancel(ctx), market)
+		if err != nil {
+			return entity.Depth{}, err
+		}
+		// Снимок из репозитория может быть сколь угодно старым, поэтому в кэш попадают только ответы источника.
+		// При фоновом опросе кэш наполняет Collect
+		if !c.serveFromStore {
+			c.store(market, dept)
+		}
+		return dept, nil
+	})
+	if err != nil {
+		return entity.Depth{}, err
+	}
+	return v.(entity.Depth), nil
+}
+
+// Collect запрашивает котировки у источника и обновляет кэш
+func (c *CachedService) Collect(ctx context.Context, market string) (entity.Depth, error) {
+	dept, err := c.Service.Collect(ctx, market)
+	if err != nil {
+		return entity.Depth{}, err
+	}
+	c.store(dept.Market, dept)
+	return dept, nil
+}
+
+func (c *CachedService) lookup(market string) (entity.Depth, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	entry, ok := c.entries[market]
+	if !ok || c.now().Sub(entry.fetchedAt) >= c.maxAge {
+		return entity.Depth{}, false
+	}
+	return entry.dept, true
+}
+
+func (c *CachedService) store(market string, dept entity.Depth) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[market] = cacheEntry{dept: dept, fetchedAt: c.now()}
+}
@@ -0,0 +1,117 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedService_GetRates(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
+
+	cached := NewCachedService(NewService(mockRepo, mockSrc, Config{}), time.Minute)
+	now := time.Now()
+	cached.now = func() time.Time { return now }
+
+	// Первый запрос идет в источник, второй отдается из кэша
+	first, err := cached.GetRates(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	second, err := cached.GetRates(context.Background(), "USDT-RUB")
+	assert.NoError(t, err)
+	assert.Equal(t, first, second)
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 1)
+
+	// Снимок старше maxAge запрашивается заново
+	now = now.Add(time.Minute)
+	_, err = cached.GetRates(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 2)
+}
+
+func TestCachedService_Singleflight(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	release := make(chan struct{})
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").
+		Run(func(mock.Arguments) { <-release }).
+		Return(testDepth, nil)
+
+	cached := NewCachedService(NewService(mockRepo, mockSrc, Config{}), time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			dept, err := cached.GetRates(context.Background(), "usdtrub")
+			assert.NoError(t, err)
+			assert.Equal(t, int64(1234567890), dept.Timestamp)
+		}()
+	}
+	time.Sleep(20 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	// Одновременные промахи схлопнулись в один запрос к источнику
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 1)
+}
+
+func TestCachedService_Collect(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
+
+	cached := NewCachedService(NewService(mockRepo, mockSrc, Config{}), time.Minute)
+
+	// Опрос по расписанию прогревает кэш
+	_, err := cached.Collect(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+
+	dept, err := cached.GetRates(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	assert.Equal(t, entity.Order{Price: entity.MustDecimal("100"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("100"), Type: "limit"}, dept.Asks)
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 1)
+}
+
+func TestCachedService_StoreReadsNotCached(t *testing.T) {
+	stored := depthWithAsk("100")
+	stored.Timestamp = time.Now().Add(-time.Hour).Unix()
+	mockRepo := new(MockRepositer)
+	mockRepo.On("LatestDepth", mock.Anything, "usdtrub").Return(stored, nil)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
+
+	cached := NewCachedService(NewService(mockRepo, mockSrc, Config{ServeFromStore: true}), time.Minute)
+
+	// Старый снимок из репозитория не выдается из кэша как свежий
+	for i := 0; i < 2; i++ {
+		dept, err := cached.GetRates(context.Background(), "usdtrub")
+		assert.NoError(t, err)
+		assert.Equal(t, stored.Timestamp, dept.Timestamp)
+	}
+	mockRepo.AssertNumberOfCalls(t, "LatestDepth", 2)
+
+	// Ответ источника при фоновом опросе кэшируется
+	_, err := cached.Collect(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	dept, err := cached.GetRates(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1234567890), dept.Timestamp)
+	mockRepo.AssertNumberOfCalls(t, "LatestDepth", 2)
+}
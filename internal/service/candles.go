@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"rates/internal/entity"
+	"time"
+
+	"go.opentelemetry.io/otel"
+)
+
+// MaxCandles - максимальное количество свечей в одном ответе
+const MaxCandles = 5000
+
+// candleIntervals - поддерживаемые интервалы свечей в секундах
+var candleIntervals = map[string]int64{
+	"1m": 60,
+	"5m": 5 * 60,
+	"1h": 60 * 60,
+	"1d": 24 * 60 * 60,
+}
+
+// GetCandles возвращает OHLC свечи по сохраненным котировкам одной стороны стакана.
+// Без To берется текущее время, без From - последние MaxCandles интервалов
+func (s Service) GetCandles(ctx context.Context, query entity.CandleQuery) (entity.CandleSeries, error) {
+	log.Debug("Starting GetCandles request")
+
+	market, err := s.resolveMarket(query.Market)
+	if err != nil {
+		return entity.CandleSeries{}, err
+	}
+	side, err := normalizeSide(query.Side)
+	if err != nil {
+		return entity.CandleSeries{}, err
+	}
+	if side == "" {
+		return entity.CandleSeries{}, fmt.Errorf("%w: side is required", ErrInvalidArgument)
+	}
+	interval, ok := candleIntervals[query.Interval]
+	if !ok {
+		return entity.CandleSeries{}, fmt.Errorf("%w: unsupported interval %q", ErrInvalidArgument, query.Interval)
+	}
+
+	filter := entity.CandleFilter{
+		Market:   market,
+		Side:     side,
+		Interval: interval,
+		From:     query.From,
+		To:       query.To,
+	}
+	if filter.To == 0 {
+		filter.To = time.Now().Unix()
+	}
+	if filter.From == 0 {
+		filter.From = max(filter.To-interval*(MaxCandles-1), 0)
+	}
+	if filter.From < 0 || filter.From > filter.To {
+		return entity.CandleSeries{}, fmt.Errorf("%w: invalid time range [%d, %d]", ErrInvalidArgument, query.From, query.To)
+	}
+	if (filter.To-filter.From)/interval >= MaxCandles {
+		return entity.CandleSeries{}, fmt.Errorf("%w: time range exceeds %d candles of %s", ErrInvalidArgument, MaxCandles, query.Interval)
+	}
+
+	tracer := otel.Tracer("service.GetCandles")
+	ctx, span := tracer.Start(ctx, "Service")
+	defer span.End()
+
+	candles, err := s.rep.GetCandles(ctx, filter)
+	if err != nil {
+		return entity.CandleSeries{}, err
+	}
+	return entity.CandleSeries{Market: market, Side: side, Interval: query.Interval, Candles: candles}, nil
+}
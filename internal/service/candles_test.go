@@ -0,0 +1,37 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetCandles(t *testing.T) {
+	candles := []entity.Candle{{Timestamp: 0, Open: entity.MustDecimal("90"), High: entity.MustDecimal("92"), Low: entity.MustDecimal("89"), Close: entity.MustDecimal("91"), Volume: entity.MustDecimal("12.5"), Count: 3}}
+
+	mockRepo := new(MockRepositer)
+	mockRepo.On("GetCandles", mock.Anything, entity.CandleFilter{
+		Market: "usdtrub", Side: "bids", Interval: 300, From: 0, To: 3600,
+	}).Return(candles, nil)
+
+	service := NewService(mockRepo, new(MockRateSource), Config{})
+
+	got, err := service.GetCandles(context.Background(), entity.CandleQuery{
+		Market: "USDT-RUB", Side: "bid", Interval: "5m", From: 0, To: 3600,
+	})
+	assert.NoError(t, err)
+	// Ответ содержит нормализованные рынок и сторону
+	assert.Equal(t, entity.CandleSeries{Market: "usdtrub", Side: "bids", Interval: "5m", Candles: candles}, got)
+
+	// Неподдерживаемый интервал и слишком большой диапазон
+	_, err = service.GetCandles(context.Background(), entity.CandleQuery{Side: "bid", Interval: "2m"})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+	_, err = service.GetCandles(context.Background(), entity.CandleQuery{Side: "bid", Interval: "1m", From: 1, To: 1 + 60*MaxCandles})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+
+	mockRepo.AssertExpectations(t)
+}
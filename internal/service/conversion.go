@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rates/internal/entity"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+// QuoteConversion рассчитывает исполнение сделки на объем query.Amount по полному стакану источника.
+// Покупка проходит по asks, продажа по bids, уровень за уровнем от лучшей цены
+func (s Service) QuoteConversion(ctx context.Context, query entity.ConversionQuery) (entity.Conversion, error) {
+	log.Debug("Starting QuoteConversion request")
+
+	market, err := s.resolveMarket(query.Market)
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+	side, err := normalizeTradeSide(query.Side)
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+	amount, err := entity.ParseDecimal(query.Amount)
+	if err != nil {
+		return entity.Conversion{}, fmt.Errorf("%w: amount: %v", ErrInvalidArgument, err)
+	}
+	if amount.Sign() <= 0 {
+		return entity.Conversion{}, fmt.Errorf("%w: amount must be positive", ErrInvalidArgument)
+	}
+
+	tracer := otel.Tracer("service.QuoteConversion")
+	ctx, span := tracer.Start(ctx, "Service")
+	defer span.End()
+
+	data, err := s.fetchDepth(ctx, market)
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+
+	levels := data.Asks
+	if side == "sell" {
+		levels = data.Bids
+	}
+	conv, err := walkBook(levels, amount)
+	if errors.Is(err, entity.ErrDecimalOverflow) {
+		return entity.Conversion{}, fmt.Errorf("%w: amount %s is too large to quote", ErrInvalidArgument, amount)
+	}
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+	conv.Market = market
+	conv.Side = side
+	conv.Timestamp = data.Timestamp
+	return conv, nil
+}
+
+// walkBook набирает объем amount по уровням стакана, начиная с лучшего.
+// Если стоимость не помещается в Decimal, возвращает entity.ErrDecimalOverflow
+func walkBook(levels []entity.Order, amount entity.Decimal) (entity.Conversion, error) {
+	conv := entity.Conversion{Amount: amount}
+	if len(levels) == 0 {
+		return conv, nil
+	}
+	conv.BestPrice = levels[0].Price
+
+	remaining := amount
+	for _, level := range levels {
+		if remaining.Sign() <= 0 {
+			break
+		}
+		take := level.Volume
+		if take.Cmp(remaining) > 0 {
+			take = remaining
+		}
+		cost, err := take.Mul(level.Price)
+		if err != nil {
+			return entity.Conversion{}, err
+		}
+		conv.TotalCost, err = conv.TotalCost.AddChecked(cost)
+		if err != nil {
+			return entity.Conversion{}, err
+		}
+		conv.Filled = conv.Filled.Add(take)
+		remaining = remaining.Sub(take)
+		conv.Levels++
+	}
+	conv.Sufficient = remaining.Sign() <= 0
+
+	if conv.Filled.IsZero() {
+		return conv, nil
+	}
+	avg, err := conv.TotalCost.Quo(conv.Filled)
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+	conv.AveragePrice = avg
+	// Для покупки и продажи средняя цена всегда хуже лучшей, поэтому проскальзывание берется по модулю
+	conv.Slippage = avg.Sub(conv.BestPrice).Abs()
+	// slippage * 10000 / best с одним округлением и без ограничения произведения
+	conv.SlippageBps, err = conv.Slippage.MulQuoRound(basisPoints, conv.BestPrice, SpreadBpsPlaces)
+	if err != nil {
+		return entity.Conversion{}, err
+	}
+	return conv, nil
+}
+
+// normalizeTradeSide приводит направление сделки к buy или sell.
+// Сторона стакана тоже допускается: asks соответствует покупке, bids - продаже
+func normalizeTradeSide(side string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(side)) {
+	case "buy", "ask", "asks":
+		return "buy", nil
+	case "sell", "bid", "bids":
+		return "sell", nil
+	case "":
+		return "", fmt.Errorf("%w: side is required", ErrInvalidArgument)
+	default:
+		return "", fmt.Errorf("%w: unknown side %q", ErrInvalidArgument, side)
+	}
+}
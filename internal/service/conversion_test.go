@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var conversionDepth = entity.DepthRequest{
+	Timestamp: 1234567890,
+	Asks: []entity.Order{
+		{Price: entity.MustDecimal("100"), Volume: entity.MustDecimal("1")},
+		{Price: entity.MustDecimal("101"), Volume: entity.MustDecimal("2")},
+		{Price: entity.MustDecimal("105"), Volume: entity.MustDecimal("10")},
+	},
+	Bids: []entity.Order{
+		{Price: entity.MustDecimal("90"), Volume: entity.MustDecimal("1")},
+		{Price: entity.MustDecimal("80"), Volume: entity.MustDecimal("1")},
+	},
+}
+
+func TestQuoteConversion(t *testing.T) {
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(conversionDepth, nil)
+
+	service := NewService(new(MockRepositer), mockSrc, Config{})
+
+	// 1 по 100, 2 по 101 и 0.5 по 105
+	conv, err := service.QuoteConversion(context.Background(), entity.ConversionQuery{
+		Market: "usdtrub", Side: "buy", Amount: "3.5",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "buy", conv.Side)
+	assert.Equal(t, int64(1234567890), conv.Timestamp)
+	assert.Equal(t, "354.5", conv.TotalCost.String())
+	assert.Equal(t, "101.28571429", conv.AveragePrice.String())
+	assert.Equal(t, "1.28571429", conv.Slippage.String())
+	assert.Equal(t, "128.57", conv.SlippageBps.String())
+	assert.Equal(t, 3, conv.Levels)
+	assert.True(t, conv.Sufficient)
+
+	// На продажу ликвидности не хватает: набирается только 2 из 5
+	conv, err = service.QuoteConversion(context.Background(), entity.ConversionQuery{
+		Market: "usdtrub", Side: "sell", Amount: "5",
+	})
+	assert.NoError(t, err)
+	assert.False(t, conv.Sufficient)
+	assert.Equal(t, "2", conv.Filled.String())
+	assert.Equal(t, "170", conv.TotalCost.String())
+	assert.Equal(t, "85", conv.AveragePrice.String())
+	assert.Equal(t, "555.56", conv.SlippageBps.String())
+}
+
+func TestQuoteConversion_InvalidArgument(t *testing.T) {
+	mockSrc := new(MockRateSource)
+	service := NewService(new(MockRepositer), mockSrc, Config{})
+
+	queries := []entity.ConversionQuery{
+		{Side: "buy", Amount: "0"},
+		{Side: "buy", Amount: "-1"},
+		{Side: "buy", Amount: "ten"},
+		{Side: "hold", Amount: "1"},
+		{Amount: "1"},
+	}
+	for _, query := range queries {
+		_, err := service.QuoteConversion(context.Background(), query)
+		assert.ErrorIs(t, err, ErrInvalidArgument, query)
+	}
+	mockSrc.AssertNotCalled(t, "GetDepth", mock.Anything, mock.Anything)
+}
+
+func TestWalkBook_EmptySide(t *testing.T) {
+	conv, err := walkBook(nil, entity.MustDecimal("1"))
+	assert.NoError(t, err)
+	assert.False(t, conv.Sufficient)
+	assert.True(t, conv.Filled.IsZero())
+}
+
+func TestQuoteConversion_Overflow(t *testing.T) {
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(entity.DepthRequest{
+		Timestamp: 1234567890,
+		Bids:      []entity.Order{{Price: entity.MustDecimal("9"), Volume: entity.MustDecimal("1")}},
+		Asks: []entity.Order{
+			{Price: entity.MustDecimal("10"), Volume: entity.MustDecimal("1")},
+			{Price: entity.MustDecimal("2000000"), Volume: entity.MustDecimal("1000000")},
+		},
+	}, nil)
+	service := NewService(new(MockRepositer), mockSrc, Config{})
+
+	// Проскальзывание * 10000 не помещается в Decimal, итог в базисных пунктах - помещается
+	conv, err := service.QuoteConversion(context.Background(), entity.ConversionQuery{
+		Market: "usdtrub", Side: "buy", Amount: "2",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1000005", conv.AveragePrice.String())
+	assert.Equal(t, "999995000", conv.SlippageBps.String())
+
+	// Стоимость 100000 по 2000000 не представима - запрос отклоняется
+	_, err = service.QuoteConversion(context.Background(), entity.ConversionQuery{
+		Market: "usdtrub", Side: "buy", Amount: "100000",
+	})
+	assert.ErrorIs(t, err, ErrInvalidArgument)
+}
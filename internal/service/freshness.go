@@ -0,0 +1,114 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rates/internal/entity"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fetchLog - время последнего успешного получения стакана по рынку и источнику
+type fetchLog struct {
+	mu   sync.Mutex
+	last map[string]map[string]time.Time
+}
+
+func newFetchLog() *fetchLog {
+	return &fetchLog{last: make(map[string]map[string]time.Time)}
+}
+
+func (l *fetchLog) record(market, source string, ts time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	sources, ok := l.last[market]
+	if !ok {
+		sources = make(map[string]time.Time)
+		l.last[market] = sources
+	}
+	sources[source] = ts
+}
+
+func (l *fetchLog) sources(market string) map[string]time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	result := make(map[string]time.Time, len(l.last[market]))
+	for source, ts := range l.last[market] {
+		result[source] = ts
+	}
+	return result
+}
+
+// SourceFetches возвращает время последнего успешного стакана рынка по источникам
+func (s Service) SourceFetches(market string) map[string]time.Time {
+	return s.fetches.sources(entity.NormalizeMarket(market))
+}
+
+// LastSuccessfulFetch возвращает время последнего стакана рынка, принятого от любого источника, или нулевое время
+func (s Service) LastSuccessfulFetch(market string) time.Time {
+	var last time.Time
+	for _, ts := range s.SourceFetches(market) {
+		if ts.After(last) {
+			last = ts
+		}
+	}
+	return last
+}
+
+// CheckSources проверяет, что по каждому рынку из markets есть стакан не старше maxAge.
+// Рынки, которых нет в текущем списке разрешенных, пропускаются.
+// При фоновом опросе отставание означает сбой опроса, а до первого опроса возраст отсчитывается от создания сервиса.
+// При запросах к источнику по требованию клиентов устаревший рынок запрашивается у источника
+func (s Service) CheckSources(ctx context.Context, markets []string, maxAge time.Duration) error {
+	known := make(map[string]bool)
+	for _, market := range s.Markets() {
+		known[market] = true
+	}
+	errs := make([]error, len(markets))
+	var wg sync.WaitGroup
+	for i, market := range markets {
+		market = entity.NormalizeMarket(market)
+		if !known[market] {
+			continue
+		}
+		last := s.LastSuccessfulFetch(market)
+		if last.IsZero() && s.serveFromStore {
+			last = s.created
+		}
+		if s.now().Sub(last) <= maxAge {
+			continue
+		}
+		if s.serveFromStore {
+			errs[i] = s.staleError(market, last)
+			continue
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := s.fetchDepth(ctx, market); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", market, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// staleError описывает отставание рынка с временем последнего успеха каждого источника
+func (s Service) staleError(market string, last time.Time) error {
+	fetches := s.SourceFetches(market)
+	sources := make([]string, 0, len(fetches))
+	for source, ts := range fetches {
+		sources = append(sources, fmt.Sprintf("%s %s ago", source, s.now().Sub(ts).Round(time.Second)))
+	}
+	sort.Strings(sources)
+	if len(sources) == 0 {
+		sources = append(sources, "no successful fetch")
+	}
+	return fmt.Errorf("%s: last successful fetch was %s ago (%s)", market,
+		s.now().Sub(last).Round(time.Second), strings.Join(sources, ", "))
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math"
+	"rates/internal/entity"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+)
+
+const (
+	DefaultHistoryPageSize = 100
+	MaxHistoryPageSize     = 1000
+)
+
+// ErrInvalidArgument возвращается при некорректных параметрах запроса
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// GetHistory возвращает страницу сохраненных котировок по рынку за интервал [From, To]
+func (s Service) GetHistory(ctx context.Context, query entity.HistoryQuery) (entity.HistoryPage, error) {
+	log.Debug("Starting GetHistory request")
+
+	market, err := s.resolveMarket(query.Market)
+	if err != nil {
+		return entity.HistoryPage{}, err
+	}
+	side, err := normalizeSide(query.Side)
+	if err != nil {
+		return entity.HistoryPage{}, err
+	}
+
+	filter := entity.HistoryFilter{
+		Market: market,
+		Side:   side,
+		From:   query.From,
+		To:     query.To,
+		Limit:  query.PageSize,
+	}
+	if filter.To == 0 {
+		filter.To = math.MaxInt64
+	}
+	if filter.From < 0 || filter.From > filter.To {
+		return entity.HistoryPage{}, fmt.Errorf("%w: invalid time range [%d, %d]", ErrInvalidArgument, query.From, query.To)
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = DefaultHistoryPageSize
+	}
+	if filter.Limit > MaxHistoryPageSize {
+		filter.Limit = MaxHistoryPageSize
+	}
+	if query.PageToken != "" {
+		filter.AfterTimestamp, filter.AfterID, err = decodePageToken(query.PageToken)
+		if err != nil {
+			return entity.HistoryPage{}, err
+		}
+	} else {
+		// Начинаем с первой записи интервала
+		filter.AfterTimestamp, filter.AfterID = filter.From, 0
+	}
+
+	tracer := otel.Tracer("service.GetHistory")
+	ctx, span := tracer.Start(ctx, "Service")
+	defer span.End()
+
+	// Запрашиваем на одну запись больше, чтобы понять, есть ли следующая страница
+	limit := filter.Limit
+	filter.Limit++
+	records, err := s.rep.GetHistory(ctx, filter)
+	if err != nil {
+		return entity.HistoryPage{}, err
+	}
+
+	page := entity.HistoryPage{Records: records}
+	if len(records) > limit {
+		page.Records = records[:limit]
+		last := page.Records[limit-1]
+		page.NextPageToken = encodePageToken(last.Timestamp, last.ID)
+	}
+	return page, nil
+}
+
+// normalizeSide приводит сторону стакана к формату хранения: "ask" -> "asks", "bid" -> "bids"
+func normalizeSide(side string) (string, error) {
+	switch strings.ToLower(strings.TrimSpace(side)) {
+	case "":
+		return "", nil
+	case "ask", "asks":
+		return "asks", nil
+	case "bid", "bids":
+		return "bids", nil
+	default:
+		return "", fmt.Errorf("%w: unknown side %q", ErrInvalidArgument, side)
+	}
+}
+
+func encodePageToken(timestamp, id int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%d:%d", timestamp, id)))
+}
+
+func decodePageToken(token string) (int64, int64, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: malformed page token", ErrInvalidArgument)
+	}
+	ts, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return 0, 0, fmt.Errorf("%w: malformed page token", ErrInvalidArgument)
+	}
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: malformed page token", ErrInvalidArgument)
+	}
+	lastID, err := strconv.ParseInt(id, 10, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: malformed page token", ErrInvalidArgument)
+	}
+	return timestamp, lastID, nil
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetHistory_Pagination(t *testing.T) {
+	records := []entity.HistoryRecord{
+		{ID: 1, Market: "usdtrub", Side: "asks", Timestamp: 100},
+		{ID: 2, Market: "usdtrub", Side: "asks", Timestamp: 110},
+		{ID: 3, Market: "usdtrub", Side: "asks", Timestamp: 120},
+	}
+
+	mockRepo := new(MockRepositer)
+	mockRepo.On("GetHistory", mock.Anything, entity.HistoryFilter{
+		Market: "usdtrub", Side: "asks", From: 100, To: math.MaxInt64,
+		AfterTimestamp: 100, AfterID: 0, Limit: 3,
+	}).Return(records, nil)
+	mockRepo.On("GetHistory", mock.Anything, entity.HistoryFilter{
+		Market: "usdtrub", Side: "asks", From: 100, To: math.MaxInt64,
+		AfterTimestamp: 110, AfterID: 2, Limit: 3,
+	}).Return(records[2:], nil)
+
+	service := NewService(mockRepo, new(MockRateSource), Config{})
+
+	// Первая страница заполнена полностью и содержит токен следующей
+	page, err := service.GetHistory(context.Background(), entity.HistoryQuery{
+		Market: "usdtrub", Side: "ask", From: 100, PageSize: 2,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, page.Records, 2)
+	assert.NotEmpty(t, page.NextPageToken)
+
+	// Последняя страница без токена
+	page, err = service.GetHistory(context.Background(), entity.HistoryQuery{
+		Market: "usdtrub", Side: "ask", From: 100, PageSize: 2, PageToken: page.NextPageToken,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, page.Records, 1)
+	assert.Equal(t, int64(3), page.Records[0].ID)
+	assert.Empty(t, page.NextPageToken)
+
+	mockRepo.AssertExpectations(t)
+}
+
+func TestGetHistory_InvalidArgument(t *testing.T) {
+	service := NewService(new(MockRepositer), new(MockRateSource), Config{})
+
+	queries := []entity.HistoryQuery{
+		{Side: "middle"},
+		{From: 200, To: 100},
+		{PageToken: "not a token"},
+	}
+	for _, query := range queries {
+		_, err := service.GetHistory(context.Background(), query)
+		assert.ErrorIs(t, err, ErrInvalidArgument)
+	}
+}
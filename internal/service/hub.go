@@ -0,0 +1,141 @@
+package service
+
+import (
+	"rates/internal/entity"
+	"sync"
+)
+
+// DefaultSubscriberBuffer - размер буфера подписчика, если он не задан в конфигурации
+const DefaultSubscriberBuffer = 16
+
+type subscriber struct {
+	updates chan entity.Depth
+	// slow выставляется, если подписчик отключен из-за переполнения буфера
+	slow bool
+}
+
+// hub раздает обновления лучших котировок подписчикам рынка.
+// Один опрос источника рассылается всем подписчикам, медленные подписчики отключаются
+type hub struct {
+	buffer int
+
+	mu   sync.Mutex
+	subs map[string]map[*subscriber]struct{}
+	last map[string]entity.Depth
+	// closed выставляется при остановке сервиса, после нее подписка невозможна
+	closed bool
+}
+
+func newHub(buffer int) *hub {
+	if buffer <= 0 {
+		buffer = DefaultSubscriberBuffer
+	}
+	return &hub{
+		buffer: buffer,
+		subs:   make(map[string]map[*subscriber]struct{}),
+		last:   make(map[string]entity.Depth),
+	}
+}
+
+// subscribe подписывает на обновления рынка. Последний известный снимок отправляется сразу
+func (h *hub) subscribe(market string) (*subscriber, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return nil, ErrShuttingDown
+	}
+
+	sub := &subscriber{updates: make(chan entity.Depth, h.buffer)}
+	if dept, ok := h.last[market]; ok {
+		sub.updates <- dept
+	}
+	if h.subs[market] == nil {
+		h.subs[market] = make(map[*subscriber]struct{})
+	}
+	h.subs[market][sub] = struct{}{}
+	return sub, nil
+}
+
+// unsubscribe отписывает подписчика и закрывает его канал
+func (h *hub) unsubscribe(market string, sub *subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subs[market][sub]; !ok {
+		return
+	}
+	delete(h.subs[market], sub)
+	close(sub.updates)
+}
+
+// publish рассылает снимок подписчикам, если лучшие ask или bid изменились
+func (h *hub) publish(dept entity.Depth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if last, ok := h.last[dept.Market]; ok && last.Asks == dept.Asks && last.Bids == dept.Bids {
+		return
+	}
+	h.last[dept.Market] = dept
+
+	for sub := range h.subs[dept.Market] {
+		select {
+		case sub.updates <- dept:
+		default:
+			// Подписчик не успевает вычитывать обновления - отключаем его
+			log.Warnf("Disconnecting slow subscriber of %s", dept.Market)
+			sub.slow = true
+			delete(h.subs[dept.Market], sub)
+			close(sub.updates)
+		}
+	}
+}
+
+// seed запоминает снимок рынка, если по нему еще нет обновлений
+func (h *hub) seed(dept entity.Depth) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.last[dept.Market]; !ok {
+		h.last[dept.Market] = dept
+	}
+}
+
+// forget удаляет последний снимок рынка, чтобы новый подписчик не получил устаревшие котировки
+func (h *hub) forget(market string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	delete(h.last, market)
+}
+
+// close отключает всех подписчиков и запрещает новые подписки
+func (h *hub) close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for _, subs := range h.subs {
+		for sub := range subs {
+			close(sub.updates)
+		}
+	}
+	h.subs = make(map[string]map[*subscriber]struct{})
+}
+
+// isClosed сообщает, остановлена ли рассылка
+func (h *hub) isClosed() bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return h.closed
+}
+
+// isSlow сообщает, был ли подписчик отключен из-за переполнения буфера
+func (h *hub) isSlow(sub *subscriber) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return sub.slow
+}
@@ -2,13 +2,14 @@ package service
 
 import (
 	"context"
-	"encoding/json"
-	"io"
-	"net/http"
+	"errors"
+	"fmt"
 	"rates/internal/entity"
 	"rates/internal/infrastructure/metrics"
 	"rates/internal/repository"
+	"rates/internal/source"
 	"rates/pkg/logger"
+	"sync/atomic"
 	"time"
 
 	"go.opentelemetry.io/otel"
@@ -18,82 +19,307 @@ var (
 	log = logger.Logger().Named("service").Sugar()
 )
 
+// DefaultMarket используется, если в конфигурации не задан список рынков
+const DefaultMarket = "usdtrub"
+
+// ErrUnknownMarket возвращается для рынка, которого нет в списке разрешенных
+var ErrUnknownMarket = errors.New("unknown market")
+
+// DefaultOrderBookLimit - максимальная глубина стакана, если она не задана в конфигурации
+const DefaultOrderBookLimit = 50
+
+type Config struct {
+	// Markets - список разрешенных рынков, первый используется по умолчанию
+	Markets []string
+	// OrderBookLimit - максимальное количество уровней стакана на сторону
+	OrderBookLimit int
+	// ServeFromStore - GetRates отдает последний снимок из репозитория вместо запроса к источнику
+	ServeFromStore bool
+	// SubscriberBuffer - количество обновлений, которое подписчик может не вычитать до отключения
+	SubscriberBuffer int
+	// SubscribePollInterval - интервал опроса рынков с подписчиками, если фоновый опросчик выключен
+	SubscribePollInterval time.Duration
+	// MarketSources - источники отдельных рынков. Для остальных рынков используется основной источник
+	MarketSources map[string]source.RateSource
+	// AggregateSources - источники сводного курса. Если не заданы, используется основной источник
+	AggregateSources []source.RateSource
+	// AggregateMaxAge - максимальный возраст котировки источника в сводном курсе, 0 отключает проверку
+	AggregateMaxAge time.Duration
+	// MaxQuoteAge - максимальный возраст снимка источника, который можно сохранить, 0 отключает проверку
+	MaxQuoteAge time.Duration
+	// OutlierBps - допустимое отклонение середины источника от медианы в базисных пунктах
+	OutlierBps int64
+}
+
 type Service struct {
 	rep repository.Repositer
+	src source.RateSource
+	// Список рынков заменяется целиком при перезагрузке конфигурации
+	markets        *atomic.Pointer[[]string]
+	orderBookLimit int
+	serveFromStore bool
+	hub            *hub
+	// Опрос рынков с подписчиками, nil при фоновом опросе всех рынков
+	pollers       *marketPollers
+	marketSources map[string]source.RateSource
+	maxQuoteAge   time.Duration
+
+	aggSources []source.RateSource
+	aggMaxAge  time.Duration
+	outlierBps entity.Decimal
+	now        func() time.Time
+	// Время последнего успешного получения стакана по рынкам и источникам
+	fetches *fetchLog
+	created time.Time
+}
+
+func NewService(rep repository.Repositer, src source.RateSource, cfg Config) *Service {
+	orderBookLimit := cfg.OrderBookLimit
+	if orderBookLimit <= 0 {
+		orderBookLimit = DefaultOrderBookLimit
+	}
+	marketSources := make(map[string]source.RateSource, len(cfg.MarketSources))
+	for market, src := range cfg.MarketSources {
+		marketSources[entity.NormalizeMarket(market)] = src
+	}
+	aggSources := cfg.AggregateSources
+	if len(aggSources) == 0 {
+		aggSources = []source.RateSource{src}
+	}
+	outlierBps := cfg.OutlierBps
+	if outlierBps <= 0 {
+		outlierBps = DefaultOutlierBps
+	}
+	s := &Service{
+		rep:            rep,
+		src:            src,
+		markets:        new(atomic.Pointer[[]string]),
+		orderBookLimit: orderBookLimit,
+		serveFromStore: cfg.ServeFromStore,
+		hub:            newHub(cfg.SubscriberBuffer),
+		marketSources:  marketSources,
+		maxQuoteAge:    cfg.MaxQuoteAge,
+		aggSources:     aggSources,
+		aggMaxAge:      cfg.AggregateMaxAge,
+		outlierBps:     entity.NewDecimalFromInt(outlierBps),
+		now:            time.Now,
+		fetches:        newFetchLog(),
+		created:        time.Now(),
+	}
+	s.SetMarkets(cfg.Markets)
+	if !cfg.ServeFromStore {
+		s.pollers = newMarketPollers(s.hub, cfg.SubscribePollInterval)
+	}
+	return s
+}
+
+// SetMarkets заменяет список разрешенных рынков без повторов. Пустой список заменяется рынком по умолчанию
+func (s Service) SetMarkets(markets []string) {
+	normalized := entity.NormalizeMarkets(markets)
+	if len(normalized) == 0 {
+		normalized = []string{DefaultMarket}
+	}
+	s.markets.Store(&normalized)
 }
 
-func NewService(rep repository.Repositer) *Service {
-	return &Service{rep: rep}
+// Markets возвращает список разрешенных рынков, первый используется по умолчанию
+func (s Service) Markets() []string {
+	return *s.markets.Load()
+}
+
+// resolveMarket проверяет рынок по списку разрешенных. Пустой рынок заменяется рынком по умолчанию
+func (s Service) resolveMarket(market string) (string, error) {
+	markets := s.Markets()
+	market = entity.NormalizeMarket(market)
+	if market == "" {
+		return markets[0], nil
+	}
+	for _, m := range markets {
+		if m == market {
+			return market, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %q", ErrUnknownMarket, market)
 }
 
-func (s Service) GetRates(ctx context.Context) (entity.Depth, error) {
+func (s Service) GetRates(ctx context.Context, market string) (entity.Depth, error) {
 	log.Debug("Starting GetRates request")
+
+	market, err := s.resolveMarket(market)
+	if err != nil {
+		return entity.Depth{}, err
+	}
+
+	// Если котировки собирает фоновый опросчик, отдаем последний сохраненный снимок
+	if s.serveFromStore {
+		dept, err := s.rep.LatestDepth(ctx, market)
+		if err == nil {
+			return dept, nil
+		}
+		if !errors.Is(err, repository.ErrNotFound) {
+			return entity.Depth{}, err
+		}
+		log.Infof("No stored snapshot for %s yet, fetching from source", market)
+	}
+	return s.collect(ctx, market)
+}
+
+// Collect запрашивает котировки у источника и сохраняет их в репозиторий
+func (s Service) Collect(ctx context.Context, market string) (entity.Depth, error) {
+	market, err := s.resolveMarket(market)
+	if err != nil {
+		return entity.Depth{}, err
+	}
+	return s.collect(ctx, market)
+}
+
+func (s Service) collect(ctx context.Context, market string) (entity.Depth, error) {
 	// Создание трассера для ослеживания времени получения данных от сервиса
 	tracer := otel.Tracer("service.GetRacer")
 	ctx, span := tracer.Start(ctx, "Service")
 	defer span.End()
 
-	// Метрика начала запроса к Garantex
-	startTotal := time.Now()
-
-	var dept entity.Depth
-	// Get запрос к Garantex для получения свеч пары USDT-RUB
-	resp, err := http.Get("https://garantex.org/api/v2/depth?market=usdtrub")
-	log.Info("call a resp")
+	data, err := s.fetchDepth(ctx, market)
 	if err != nil {
-		// Метрика Prometheus неудачных запросов к Garantex
-		metrics.StatusRequestToGarantex("error")
-		log.Errorf("Error during HTTP request: %v", err)
 		return entity.Depth{}, err
 	}
-	// Фиксация времени запроса к Garantex
-	metrics.TimeRequestToGarantex("http_request", time.Since(startTotal).Seconds())
-	//  Метрика Prometheus удачных запросов к Garantex
-	metrics.StatusRequestToGarantex("success")
 
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
+	// fetchDepth гарантирует непустые стороны стакана
+	dept, err := withSpread(entity.Depth{
+		Market: market,
+		Asks: entity.Order{
+			Price:  data.Asks[0].Price,
+			Volume: data.Asks[0].Volume,
+			Amount: data.Asks[0].Amount,
+			Factor: data.Asks[0].Factor,
+			Type:   data.Asks[0].Type,
+		},
+		Bids: entity.Order{
+			Price:  data.Bids[0].Price,
+			Volume: data.Bids[0].Volume,
+			Amount: data.Bids[0].Amount,
+			Factor: data.Bids[0].Factor,
+			Type:   data.Bids[0].Type,
+		},
+		Timestamp: data.Timestamp,
+		Source:    data.Source,
+	})
 	if err != nil {
-		log.Errorf("Error reading response body: %v", err)
 		return entity.Depth{}, err
 	}
-
-	var data entity.DepthRequest
-	if err := json.Unmarshal(body, &data); err != nil {
-		log.Errorf("Error unmarshalling response data: %v", err)
+	// Метрика начала выполненеия запросов к репозиторию
+	startTotalDB := time.Now()
+	err = s.rep.InsertDepth(ctx, dept)
+	if err != nil {
 		return entity.Depth{}, err
 	}
-	if len(data.Asks) > 0 && len(data.Bids) > 0 && data.Timestamp != 0 {
-		dept = entity.Depth{
-			Asks: entity.Order{
-				Price:  data.Asks[0].Price,
-				Volume: data.Asks[0].Volume,
-				Amount: data.Asks[0].Amount,
-				Factor: data.Asks[0].Factor,
-				Type:   data.Asks[0].Type,
-			},
-			Bids: entity.Order{
-				Price:  data.Bids[0].Price,
-				Volume: data.Bids[0].Volume,
-				Amount: data.Bids[0].Amount,
-				Factor: data.Bids[0].Factor,
-				Type:   data.Bids[0].Type,
-			},
-			Timestamp: data.Timestamp,
-		}
+	// Фиксация времени запроса к репозиторию
+	metrics.TimeRequestToDB("insert_to_db", time.Since(startTotalDB).Seconds())
+
+	s.hub.publish(dept)
+	return dept, nil
+}
+
+// GetOrderBook возвращает стакан глубиной limit уровней на сторону и сохраняет его снимок
+func (s Service) GetOrderBook(ctx context.Context, market string, limit int) (entity.OrderBook, error) {
+	log.Debug("Starting GetOrderBook request")
+
+	market, err := s.resolveMarket(market)
+	if err != nil {
+		return entity.OrderBook{}, err
 	}
-	// Метрика начала выполненеия запросов к репозиторию
+	// Глубина ограничена сверху значением из конфигурации
+	if limit <= 0 || limit > s.orderBookLimit {
+		limit = s.orderBookLimit
+	}
+
+	tracer := otel.Tracer("service.GetOrderBook")
+	ctx, span := tracer.Start(ctx, "Service")
+	defer span.End()
+
+	data, err := s.fetchDepth(ctx, market)
+	if err != nil {
+		return entity.OrderBook{}, err
+	}
+
+	book := entity.OrderBook{
+		Market:    market,
+		Timestamp: data.Timestamp,
+		Asks:      truncateOrders(data.Asks, limit),
+		Bids:      truncateOrders(data.Bids, limit),
+	}
+
 	startTotalDB := time.Now()
-	err = s.rep.InsertAsks(ctx, dept)
+	if err := s.rep.InsertOrderBook(ctx, book); err != nil {
+		return entity.OrderBook{}, err
+	}
+	metrics.TimeRequestToDB("insert_order_book", time.Since(startTotalDB).Seconds())
+	return book, nil
+}
+
+// fetchDepth запрашивает полный стакан у источника рынка и проверяет, что его можно сохранить:
+// обе стороны не пустые, ask >= bid и снимок не старше maxQuoteAge
+func (s Service) fetchDepth(ctx context.Context, market string) (entity.DepthRequest, error) {
+	src := s.sourceFor(market)
+	data, err := fetchDepthFrom(ctx, src, market)
 	if err != nil {
-		return entity.Depth{}, err
+		return entity.DepthRequest{}, err
+	}
+	if err := data.CheckBook(); err != nil {
+		log.Errorf("Rejected depth of %s from %s: %v", market, src.Name(), err)
+		return entity.DepthRequest{}, err
+	}
+	if s.maxQuoteAge > 0 {
+		if age := s.now().Sub(time.Unix(data.Timestamp, 0)); age > s.maxQuoteAge {
+			log.Errorf("Rejected depth of %s from %s: snapshot is %s old", market, src.Name(), age)
+			return entity.DepthRequest{}, fmt.Errorf("%w: %s snapshot is %s old", entity.ErrStaleQuote, market, age)
+		}
+	}
+	name := data.Source
+	if name == "" {
+		name = src.Name()
 	}
+	s.fetches.record(market, name, s.now())
+	return data, nil
+}
 
-	err = s.rep.InsertBids(ctx, dept)
+// sourceFor возвращает источник рынка из конфигурации или основной источник
+func (s Service) sourceFor(market string) source.RateSource {
+	if src, ok := s.marketSources[market]; ok {
+		return src
+	}
+	return s.src
+}
+
+// fetchDepthFrom запрашивает полный стакан у источника src и проверяет его
+func fetchDepthFrom(ctx context.Context, src source.RateSource, market string) (entity.DepthRequest, error) {
+	// Метрика начала запроса к источнику
+	startTotal := time.Now()
+
+	// Запрос к источнику котировок для получения стакана по рынку
+	data, err := src.GetDepth(ctx, market)
 	if err != nil {
-		return entity.Depth{}, err
+		// Метрика Prometheus неудачных запросов к источнику
+		metrics.StatusRequestToSource(src.Name(), "error")
+		log.Errorf("Error fetching depth from %s: %v", src.Name(), err)
+		return entity.DepthRequest{}, err
 	}
-	// Фиксация времени запроса к репозиторию
-	metrics.TimeRequestToDB("insert_to_db", time.Since(startTotalDB).Seconds())
-	return dept, nil
+	// Фиксация времени запроса к источнику
+	metrics.TimeRequestToSource(src.Name(), "http_request", time.Since(startTotal).Seconds())
+	// Метрика Prometheus удачных запросов к источнику
+	metrics.StatusRequestToSource(src.Name(), "success")
+
+	// Некорректные цены и объемы не должны попасть в расчеты и базу данных
+	if err := data.Validate(); err != nil {
+		log.Errorf("Invalid depth from %s: %v", src.Name(), err)
+		return entity.DepthRequest{}, err
+	}
+	return data, nil
+}
+
+func truncateOrders(orders []entity.Order, limit int) []entity.Order {
+	if len(orders) > limit {
+		orders = orders[:limit]
+	}
+	return orders
 }
@@ -4,7 +4,10 @@ import (
 	"context"
 	"errors"
 	"rates/internal/entity"
+	"rates/internal/repository"
+	"rates/internal/source"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -14,56 +17,370 @@ type MockRepositer struct {
 	mock.Mock
 }
 
-func (m *MockRepositer) InsertAsks(ctx context.Context, dept entity.Depth) error {
+func (m *MockRepositer) InsertDepth(ctx context.Context, dept entity.Depth) error {
 	args := m.Called(ctx, dept)
 	return args.Error(0)
 }
 
-func (m *MockRepositer) InsertBids(ctx context.Context, dept entity.Depth) error {
-	args := m.Called(ctx, dept)
+func (m *MockRepositer) InsertOrderBook(ctx context.Context, book entity.OrderBook) error {
+	args := m.Called(ctx, book)
 	return args.Error(0)
 }
 
+func (m *MockRepositer) LatestDepth(ctx context.Context, market string) (entity.Depth, error) {
+	args := m.Called(ctx, market)
+	return args.Get(0).(entity.Depth), args.Error(1)
+}
+
+func (m *MockRepositer) GetHistory(ctx context.Context, filter entity.HistoryFilter) ([]entity.HistoryRecord, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]entity.HistoryRecord), args.Error(1)
+}
+
+func (m *MockRepositer) GetCandles(ctx context.Context, filter entity.CandleFilter) ([]entity.Candle, error) {
+	args := m.Called(ctx, filter)
+	return args.Get(0).([]entity.Candle), args.Error(1)
+}
+
+type MockRateSource struct {
+	mock.Mock
+	name string
+}
+
+func (m *MockRateSource) Name() string {
+	if m.name != "" {
+		return m.name
+	}
+	return "mock"
+}
+
+func (m *MockRateSource) GetDepth(ctx context.Context, market string) (entity.DepthRequest, error) {
+	args := m.Called(ctx, market)
+	return args.Get(0).(entity.DepthRequest), args.Error(1)
+}
+
+var testDepth = entity.DepthRequest{
+	Timestamp: 1234567890,
+	Asks: []entity.Order{
+		{Price: entity.MustDecimal("100"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("100"), Type: "limit"},
+	},
+	Bids: []entity.Order{
+		{Price: entity.MustDecimal("90"), Volume: entity.MustDecimal("1"), Amount: entity.MustDecimal("90"), Type: "limit"},
+	},
+}
+
 func TestGetRates(t *testing.T) {
-	// Мокаем репозиторий
+	// Мокаем репозиторий и источник котировок
 	mockRepo := new(MockRepositer)
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
 
 	// Создаем сервис
-	service := NewService(mockRepo)
+	service := NewService(mockRepo, mockSrc, Config{})
 
-	// Ожидаем, что InsertAsks и InsertBids будут вызваны один раз
-	mockRepo.On("InsertAsks", mock.Anything, mock.Anything).Return(nil)
-	mockRepo.On("InsertBids", mock.Anything, mock.Anything).Return(nil)
+	// Ожидаем, что InsertDepth будет вызван один раз
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
 
 	// Запускаем тест
-	dept, err := service.GetRates(context.Background())
+	dept, err := service.GetRates(context.Background(), "")
 
 	// Проверяем что ошибок не было
 	assert.NoError(t, err)
-	assert.NotNil(t, dept)
+	assert.Equal(t, "100", dept.Asks.Price.String())
+	assert.Equal(t, "90", dept.Bids.Price.String())
+	assert.Equal(t, int64(1234567890), dept.Timestamp)
+	assert.Equal(t, "usdtrub", dept.Market)
+	// Середина и спред считаются по лучшим ценам
+	assert.Equal(t, "95", dept.Mid.String())
+	assert.Equal(t, "10", dept.Spread.String())
+	assert.Equal(t, "1052.63", dept.SpreadBps.String())
 
-	// Проверяем, что InsertAsks и InsertBids были вызваны
+	// Проверяем, что InsertDepth был вызван с рассчитанным спредом
+	mockRepo.AssertCalled(t, "InsertDepth", mock.Anything, dept)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestWithSpread(t *testing.T) {
+	dept, err := withSpread(entity.Depth{
+		Asks: entity.Order{Price: entity.MustDecimal("95.63")},
+		Bids: entity.Order{Price: entity.MustDecimal("95.61")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "95.62", dept.Mid.String())
+	assert.Equal(t, "0.02", dept.Spread.String())
+	assert.Equal(t, "2.09", dept.SpreadBps.String())
+
+	// Широкий спред при высокой цене: spread * 20000 не помещается в Decimal, итог - помещается
+	dept, err = withSpread(entity.Depth{
+		Asks: entity.Order{Price: entity.MustDecimal("2000000")},
+		Bids: entity.Order{Price: entity.MustDecimal("1000000")},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "1500000", dept.Mid.String())
+	assert.Equal(t, "1000000", dept.Spread.String())
+	assert.Equal(t, "6666.67", dept.SpreadBps.String())
+
+	// Пустой снимок остается без спреда
+	dept, err = withSpread(entity.Depth{Market: "usdtrub"})
+	assert.NoError(t, err)
+	assert.True(t, dept.Mid.IsZero())
+	assert.True(t, dept.SpreadBps.IsZero())
+}
+
 func TestGetRates_DBError(t *testing.T) {
 	// Мокаем репозиторий
 	mockRepo := new(MockRepositer)
 
-	// Ожидаем, что InsertAsks вернет ошибку
-	mockRepo.On("InsertAsks", mock.Anything, mock.Anything).Return(errors.New("DB error"))
-	// Ожидаем, что InsertBids НЕ будет вызван, потому что ошибка произошла до его вызова
-	mockRepo.On("InsertBids", mock.Anything, mock.Anything).Return(nil).Maybe()
+	// Ожидаем, что InsertDepth вернет ошибку
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(errors.New("DB error"))
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
 
 	// Создаем сервис
-	service := NewService(mockRepo)
+	service := NewService(mockRepo, mockSrc, Config{})
 
 	// Запускаем тест
-	_, err := service.GetRates(context.Background())
+	_, err := service.GetRates(context.Background(), "")
 
 	// Проверяем, что ошибка из-за работы с базой данных
 	assert.Error(t, err)
 
-	// Проверяем, что InsertAsks был вызван с ошибкой, а InsertBids не был вызван
+	// Проверяем, что InsertDepth был вызван
 	mockRepo.AssertExpectations(t)
 }
+
+func TestGetRates_SourceError(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(entity.DepthRequest{}, errors.New("network error"))
+
+	service := NewService(mockRepo, mockSrc, Config{})
+
+	_, err := service.GetRates(context.Background(), "")
+
+	// Ошибка источника возвращается без записи в базу данных
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "InsertDepth", mock.Anything, mock.Anything)
+}
+
+func TestGetRates_RejectsBook(t *testing.T) {
+	crossed := testDepth
+	crossed.Bids = []entity.Order{{Price: entity.MustDecimal("101"), Volume: entity.MustDecimal("1")}}
+	empty := testDepth
+	empty.Asks = nil
+
+	cases := map[string]struct {
+		depth entity.DepthRequest
+		want  error
+	}{
+		"empty":   {empty, entity.ErrEmptyBook},
+		"crossed": {crossed, entity.ErrCrossedBook},
+		// Снимок testDepth датирован 2009 годом
+		"stale": {testDepth, entity.ErrStaleQuote},
+	}
+	for name, tc := range cases {
+		mockRepo := new(MockRepositer)
+		mockSrc := new(MockRateSource)
+		mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(tc.depth, nil)
+
+		service := NewService(mockRepo, mockSrc, Config{MaxQuoteAge: time.Minute})
+
+		// Некорректный снимок не попадает в базу данных
+		_, err := service.GetRates(context.Background(), "")
+		assert.ErrorIs(t, err, tc.want, name)
+		mockRepo.AssertNotCalled(t, "InsertDepth", mock.Anything, mock.Anything)
+		// Отклоненный снимок не считается успешным запросом к источнику
+		assert.True(t, service.LastSuccessfulFetch("usdtrub").IsZero(), name)
+	}
+}
+
+func TestLastSuccessfulFetch(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+	mockSrc := &MockRateSource{name: "garantex"}
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
+
+	service := NewService(mockRepo, mockSrc, Config{Markets: []string{"usdtrub", "btcrub"}})
+	assert.True(t, service.LastSuccessfulFetch("usdtrub").IsZero())
+
+	_, err := service.GetRates(context.Background(), "")
+	assert.NoError(t, err)
+	assert.WithinDuration(t, time.Now(), service.LastSuccessfulFetch("usdtrub"), time.Second)
+	assert.Contains(t, service.SourceFetches("usdtrub"), "garantex")
+	// Время учитывается отдельно по рынкам
+	assert.True(t, service.LastSuccessfulFetch("btcrub").IsZero())
+}
+
+func TestCheckSources(t *testing.T) {
+	mockSrc := &MockRateSource{name: "garantex"}
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
+	mockSrc.On("GetDepth", mock.Anything, "btcrub").Return(entity.DepthRequest{}, entity.ErrUpstreamUnavailable)
+
+	// Без фонового опроса устаревшие рынки запрашиваются у источника при проверке
+	service := NewService(new(MockRepositer), mockSrc, Config{Markets: []string{"usdtrub", "btcrub"}})
+	err := service.CheckSources(context.Background(), service.Markets(), time.Minute)
+	assert.ErrorIs(t, err, entity.ErrUpstreamUnavailable)
+	assert.ErrorContains(t, err, "btcrub")
+	assert.NotContains(t, err.Error(), "usdtrub")
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 2)
+
+	// Проверяются только переданные рынки: свежий рынок повторно не запрашивается
+	assert.NoError(t, service.CheckSources(context.Background(), []string{"USDT-RUB"}, time.Minute))
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 2)
+
+	// Рынок, исключенный из списка разрешенных, не проверяется
+	service.SetMarkets([]string{"usdtrub"})
+	assert.NoError(t, service.CheckSources(context.Background(), []string{"usdtrub", "btcrub"}, time.Minute))
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 2)
+
+	// При фоновом опросе отставание одного рынка не скрывается свежими котировками другого
+	polled := NewService(new(MockRepositer), mockSrc, Config{Markets: []string{"usdtrub", "btcrub"}, ServeFromStore: true})
+	now := time.Now()
+	polled.now = func() time.Time { return now }
+	polled.fetches.record("usdtrub", "garantex", now)
+	assert.NoError(t, polled.CheckSources(context.Background(), []string{"usdtrub", "btcrub"}, time.Minute))
+
+	now = now.Add(2 * time.Minute)
+	polled.fetches.record("usdtrub", "garantex", now)
+	err = polled.CheckSources(context.Background(), []string{"usdtrub", "btcrub"}, time.Minute)
+	assert.ErrorContains(t, err, "btcrub: last successful fetch was 2m0s ago (no successful fetch)")
+	// Отставание рынка вне проверяемого набора не влияет на результат
+	assert.NoError(t, polled.CheckSources(context.Background(), []string{"usdtrub"}, time.Minute))
+	mockSrc.AssertNumberOfCalls(t, "GetDepth", 2)
+}
+
+func TestGetRates_Market(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "btcrub").Return(testDepth, nil)
+
+	service := NewService(mockRepo, mockSrc, Config{Markets: []string{"usdtrub", "BTC-RUB"}})
+
+	// Название рынка нормализуется перед проверкой
+	dept, err := service.GetRates(context.Background(), "BTC-RUB")
+	assert.NoError(t, err)
+	assert.Equal(t, "btcrub", dept.Market)
+
+	// Рынка нет в списке разрешенных
+	_, err = service.GetRates(context.Background(), "ethusdt")
+	assert.ErrorIs(t, err, ErrUnknownMarket)
+	mockSrc.AssertNotCalled(t, "GetDepth", mock.Anything, "ethusdt")
+}
+
+func TestSetMarkets(t *testing.T) {
+	service := NewService(new(MockRepositer), new(MockRateSource), Config{Markets: []string{"usdtrub"}})
+
+	service.SetMarkets([]string{"ETH-USDT", ""})
+	assert.Equal(t, []string{"ethusdt"}, service.Markets())
+	market, err := service.resolveMarket("")
+	assert.NoError(t, err)
+	assert.Equal(t, "ethusdt", market)
+	_, err = service.resolveMarket("usdtrub")
+	assert.ErrorIs(t, err, ErrUnknownMarket)
+
+	// Пустой список заменяется рынком по умолчанию
+	service.SetMarkets(nil)
+	assert.Equal(t, []string{DefaultMarket}, service.Markets())
+}
+
+func TestGetRates_MarketSource(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	backupDepth := testDepth
+	backupDepth.Source = "backup"
+	backup := &MockRateSource{name: "backup"}
+	backup.On("GetDepth", mock.Anything, "btcrub").Return(backupDepth, nil)
+	mockSrc := new(MockRateSource)
+
+	service := NewService(mockRepo, mockSrc, Config{
+		Markets:       []string{"usdtrub", "btcrub"},
+		MarketSources: map[string]source.RateSource{"BTC-RUB": backup},
+	})
+
+	// Рынок с собственным списком источников не обращается к основному источнику
+	dept, err := service.GetRates(context.Background(), "btcrub")
+	assert.NoError(t, err)
+	assert.Equal(t, "backup", dept.Source)
+	mockSrc.AssertNotCalled(t, "GetDepth", mock.Anything, mock.Anything)
+	mockRepo.AssertCalled(t, "InsertDepth", mock.Anything, dept)
+}
+
+func TestGetOrderBook(t *testing.T) {
+	level := func(price string) entity.Order {
+		return entity.Order{Price: entity.MustDecimal(price), Volume: entity.MustDecimal("1")}
+	}
+	book := entity.DepthRequest{
+		Timestamp: 1234567890,
+		Asks:      []entity.Order{level("100"), level("101"), level("102")},
+		Bids:      []entity.Order{level("90"), level("89")},
+	}
+
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertOrderBook", mock.Anything, mock.Anything).Return(nil)
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(book, nil)
+
+	service := NewService(mockRepo, mockSrc, Config{OrderBookLimit: 2})
+
+	// Запрошенная глубина больше максимальной - стакан обрезается до лимита из конфигурации
+	got, err := service.GetOrderBook(context.Background(), "usdtrub", 10)
+	assert.NoError(t, err)
+	assert.Len(t, got.Asks, 2)
+	assert.Len(t, got.Bids, 2)
+
+	got, err = service.GetOrderBook(context.Background(), "usdtrub", 1)
+	assert.NoError(t, err)
+	assert.Len(t, got.Asks, 1)
+	assert.Equal(t, "100", got.Asks[0].Price.String())
+	assert.Equal(t, "90", got.Bids[0].Price.String())
+
+	mockRepo.AssertNumberOfCalls(t, "InsertOrderBook", 2)
+}
+
+func TestGetRates_FromStore(t *testing.T) {
+	stored := entity.Depth{
+		Market:    "usdtrub",
+		Timestamp: 1234567890,
+		Asks:      entity.Order{Price: entity.MustDecimal("100")},
+		Bids:      entity.Order{Price: entity.MustDecimal("90")},
+		Mid:       entity.MustDecimal("95"),
+		Spread:    entity.MustDecimal("10"),
+		SpreadBps: entity.MustDecimal("1052.63"),
+	}
+
+	mockRepo := new(MockRepositer)
+	mockRepo.On("LatestDepth", mock.Anything, "usdtrub").Return(stored, nil)
+
+	mockSrc := new(MockRateSource)
+
+	service := NewService(mockRepo, mockSrc, Config{ServeFromStore: true})
+
+	// Последний сохраненный снимок отдается без запроса к источнику
+	dept, err := service.GetRates(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	// Середина и спред отдаются сохраненными вместе со снимком
+	assert.Equal(t, stored, dept)
+	mockSrc.AssertNotCalled(t, "GetDepth", mock.Anything, mock.Anything)
+}
+
+func TestGetRates_FromStoreEmpty(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("LatestDepth", mock.Anything, "usdtrub").Return(entity.Depth{}, repository.ErrNotFound)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+
+	mockSrc := new(MockRateSource)
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil)
+
+	service := NewService(mockRepo, mockSrc, Config{ServeFromStore: true})
+
+	// Пока опросчик ничего не сохранил, котировки запрашиваются у источника
+	dept, err := service.GetRates(context.Background(), "usdtrub")
+	assert.NoError(t, err)
+	assert.Equal(t, "100", dept.Asks.Price.String())
+	mockSrc.AssertExpectations(t)
+}
@@ -0,0 +1,38 @@
+package service
+
+import (
+	"rates/internal/entity"
+)
+
+// SpreadBpsPlaces - количество знаков после запятой у спреда в базисных пунктах
+const SpreadBpsPlaces = 2
+
+var (
+	two         = entity.NewDecimalFromInt(2)
+	basisPoints = entity.NewDecimalFromInt(10_000)
+)
+
+// withSpread заполняет середину и спред по лучшим ценам снимка.
+// Считается только для непустого снимка, иначе поля остаются нулевыми
+func withSpread(dept entity.Depth) (entity.Depth, error) {
+	ask, bid := dept.Asks.Price, dept.Bids.Price
+	if ask.Sign() <= 0 || bid.Sign() <= 0 {
+		return dept, nil
+	}
+
+	mid, err := ask.Add(bid).Quo(two)
+	if err != nil {
+		return entity.Depth{}, err
+	}
+	spread := ask.Sub(bid)
+	// spread / mid * 10000 = spread * 20000 / (ask + bid) с одним округлением и без ограничения произведения
+	bps, err := spread.MulQuoRound(basisPoints.Add(basisPoints), ask.Add(bid), SpreadBpsPlaces)
+	if err != nil {
+		return entity.Depth{}, err
+	}
+
+	dept.Mid = mid
+	dept.Spread = spread
+	dept.SpreadBps = bps
+	return dept, nil
+}
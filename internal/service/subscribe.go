@@ -0,0 +1,165 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"rates/internal/entity"
+	"rates/internal/repository"
+	"rates/internal/scheduler"
+	"sync"
+	"time"
+)
+
+// ErrSlowConsumer возвращается подписчику, который не успевал вычитывать обновления
+var ErrSlowConsumer = errors.New("subscriber is too slow")
+
+// ErrShuttingDown возвращается подписчикам при остановке сервиса
+var ErrShuttingDown = errors.New("service is shutting down")
+
+// DefaultSubscribePollInterval - интервал опроса рынков с подписчиками, если он не задан в конфигурации
+const DefaultSubscribePollInterval = 2 * time.Second
+
+// SubscribeRates передает в send текущий снимок котировок, а затем снимок при каждом изменении лучших ask
+// или bid рынка. Без фонового опросчика рынок опрашивается, пока на него есть подписчики.
+// Блокируется до отмены контекста, ошибки send, отключения медленного подписчика или остановки сервиса
+func (s Service) SubscribeRates(ctx context.Context, market string, send func(entity.Depth) error) error {
+	market, err := s.resolveMarket(market)
+	if err != nil {
+		return err
+	}
+
+	// Фоновый опросчик рассылает только изменения, текущий снимок берем из репозитория
+	if s.serveFromStore {
+		dept, err := s.rep.LatestDepth(ctx, market)
+		if err == nil {
+			s.hub.seed(dept)
+		}
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return err
+		}
+	}
+
+	sub, err := s.hub.subscribe(market)
+	if err != nil {
+		return err
+	}
+	defer s.hub.unsubscribe(market, sub)
+	// Первый опрос выполняется сразу и отправляет подписчику текущий снимок
+	if s.pollers != nil {
+		s.pollers.acquire(market)
+		defer s.pollers.release(market)
+	}
+	log.Infof("New subscriber for %s", market)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case dept, ok := <-sub.updates:
+			if !ok {
+				if s.hub.isSlow(sub) {
+					return ErrSlowConsumer
+				}
+				if s.hub.isClosed() {
+					return ErrShuttingDown
+				}
+				return nil
+			}
+			if err := send(dept); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// StartSubscriptions запускает опрос рынков с подписчиками до отмены ctx или Close.
+// collector - внешняя обертка сервиса (CachedService), чтобы опросы обновляли ее кэш.
+// Рынки, на которые подписались до запуска, начинают опрашиваться сразу после него
+func (s Service) StartSubscriptions(ctx context.Context, collector scheduler.Collector) {
+	if s.pollers != nil {
+		s.pollers.start(ctx, collector)
+	}
+}
+
+// Close завершает подписки с ошибкой ErrShuttingDown и останавливает опрос рынков с подписчиками.
+// Вызывается до остановки серверов: иначе они ждут потоки подписок, пока их не закроют клиенты
+func (s Service) Close() {
+	s.hub.close()
+	if s.pollers != nil {
+		s.pollers.stop()
+	}
+}
+
+// marketPollers опрашивает рынки, на которые есть подписчики.
+// Опрос рынка запускается с первым подписчиком и останавливается после ухода последнего
+type marketPollers struct {
+	hub      *hub
+	interval time.Duration
+
+	mu sync.Mutex
+	// scheduler создается при запуске, до него только считаются подписчики
+	scheduler   *scheduler.Scheduler
+	subscribers map[string]int
+}
+
+func newMarketPollers(hub *hub, interval time.Duration) *marketPollers {
+	if interval <= 0 {
+		interval = DefaultSubscribePollInterval
+	}
+	return &marketPollers{
+		hub:         hub,
+		interval:    interval,
+		subscribers: make(map[string]int),
+	}
+}
+
+func (p *marketPollers) start(ctx context.Context, collector scheduler.Collector) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.scheduler != nil {
+		return
+	}
+	jobs := make([]scheduler.Job, 0, len(p.subscribers))
+	for market := range p.subscribers {
+		jobs = append(jobs, scheduler.Job{Market: market, Interval: p.interval})
+	}
+	// Без сдвига первого опроса: подписчик ждет текущий снимок
+	p.scheduler = scheduler.New(collector, jobs, 0)
+	p.scheduler.Start(ctx)
+}
+
+func (p *marketPollers) stop() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.scheduler != nil {
+		p.scheduler.Stop()
+	}
+}
+
+func (p *marketPollers) acquire(market string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.subscribers[market]++
+	if p.subscribers[market] == 1 && p.scheduler != nil {
+		p.scheduler.Add(scheduler.Job{Market: market, Interval: p.interval})
+	}
+}
+
+func (p *marketPollers) release(market string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.subscribers[market]--
+	if p.subscribers[market] > 0 {
+		return
+	}
+	delete(p.subscribers, market)
+	if p.scheduler != nil {
+		p.scheduler.Remove(market)
+	}
+	// Без опроса снимок рынка устаревает
+	p.hub.forget(market)
+}
@@ -0,0 +1,218 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"rates/internal/entity"
+	"rates/internal/repository"
+	"sync/atomic"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func depthWithAsk(price string) entity.Depth {
+	return entity.Depth{
+		Market: "usdtrub",
+		Asks:   entity.Order{Price: entity.MustDecimal(price)},
+		Bids:   entity.Order{Price: entity.MustDecimal("90")},
+	}
+}
+
+// storeService - сервис с фоновым опросом и пустым репозиторием: обновления подписчикам публикует тест
+func storeService(cfg Config) *Service {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("LatestDepth", mock.Anything, "usdtrub").Return(entity.Depth{}, repository.ErrNotFound)
+	cfg.ServeFromStore = true
+	return NewService(mockRepo, new(MockRateSource), cfg)
+}
+
+func TestSubscribeRates(t *testing.T) {
+	service := storeService(Config{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan entity.Depth, 10)
+	done := make(chan error)
+	go func() {
+		done <- service.SubscribeRates(ctx, "usdtrub", func(dept entity.Depth) error {
+			received <- dept
+			return nil
+		})
+	}()
+
+	// Дожидаемся регистрации подписчика
+	require.Eventually(t, func() bool {
+		service.hub.mu.Lock()
+		defer service.hub.mu.Unlock()
+		return len(service.hub.subs["usdtrub"]) == 1
+	}, time.Second, time.Millisecond)
+
+	service.hub.publish(depthWithAsk("100"))
+	// Лучшие цены не изменились - обновление не отправляется
+	unchanged := depthWithAsk("100")
+	unchanged.Timestamp = 42
+	service.hub.publish(unchanged)
+	service.hub.publish(depthWithAsk("101"))
+
+	assert.Equal(t, "100", (<-received).Asks.Price.String())
+	assert.Equal(t, "101", (<-received).Asks.Price.String())
+	assert.Empty(t, received)
+
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+}
+
+func TestSubscribeRates_SlowConsumer(t *testing.T) {
+	service := storeService(Config{SubscriberBuffer: 1})
+
+	block := make(chan struct{})
+	done := make(chan error)
+	go func() {
+		done <- service.SubscribeRates(context.Background(), "usdtrub", func(entity.Depth) error {
+			<-block
+			return nil
+		})
+	}()
+
+	require.Eventually(t, func() bool {
+		service.hub.mu.Lock()
+		defer service.hub.mu.Unlock()
+		return len(service.hub.subs["usdtrub"]) == 1
+	}, time.Second, time.Millisecond)
+
+	// Подписчик завис на отправке первого обновления, второе занимает буфер, третье переполняет его
+	service.hub.publish(depthWithAsk("100"))
+	require.Eventually(t, func() bool {
+		service.hub.mu.Lock()
+		defer service.hub.mu.Unlock()
+		for sub := range service.hub.subs["usdtrub"] {
+			return len(sub.updates) == 0
+		}
+		return false
+	}, time.Second, time.Millisecond)
+	service.hub.publish(depthWithAsk("101"))
+	service.hub.publish(depthWithAsk("102"))
+	close(block)
+
+	assert.ErrorIs(t, <-done, ErrSlowConsumer)
+}
+
+func TestSubscribeRates_UnknownMarket(t *testing.T) {
+	service := NewService(new(MockRepositer), new(MockRateSource), Config{})
+
+	err := service.SubscribeRates(context.Background(), "ethusdt", func(entity.Depth) error { return nil })
+	assert.ErrorIs(t, err, ErrUnknownMarket)
+}
+
+func TestSubscribeRates_PollsWhileSubscribed(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	mockRepo.On("InsertDepth", mock.Anything, mock.Anything).Return(nil)
+	mockSrc := new(MockRateSource)
+	var polls atomic.Int32
+	mockSrc.On("GetDepth", mock.Anything, "usdtrub").Return(testDepth, nil).Run(func(mock.Arguments) {
+		polls.Add(1)
+	})
+	service := NewService(mockRepo, mockSrc, Config{SubscribePollInterval: 10 * time.Millisecond})
+	cached := NewCachedService(service, time.Minute)
+	defer service.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan entity.Depth, 10)
+	done := make(chan error)
+	go func() {
+		done <- service.SubscribeRates(ctx, "usdtrub", func(dept entity.Depth) error {
+			received <- dept
+			return nil
+		})
+	}()
+
+	// До запуска подписчики только учитываются, источник не опрашивается
+	require.Eventually(t, func() bool {
+		service.pollers.mu.Lock()
+		defer service.pollers.mu.Unlock()
+		return service.pollers.subscribers["usdtrub"] == 1
+	}, time.Second, time.Millisecond)
+	assert.Zero(t, polls.Load())
+	service.StartSubscriptions(context.Background(), cached)
+
+	// Первый опрос после запуска сразу отправляет текущий снимок
+	select {
+	case dept := <-received:
+		assert.Equal(t, "100", dept.Asks.Price.String())
+	case <-time.After(time.Second):
+		t.Fatal("no snapshot after subscribe")
+	}
+	require.Eventually(t, func() bool { return polls.Load() >= 3 }, time.Second, time.Millisecond)
+	// Опросы идут через внешнюю обертку и обновляют ее кэш
+	_, ok := cached.lookup("usdtrub")
+	assert.True(t, ok)
+
+	// После ухода последнего подписчика опрос останавливается, снимок рынка забывается
+	cancel()
+	assert.ErrorIs(t, <-done, context.Canceled)
+	time.Sleep(20 * time.Millisecond)
+	stopped := polls.Load()
+	time.Sleep(30 * time.Millisecond)
+	assert.Equal(t, stopped, polls.Load())
+
+	service.hub.mu.Lock()
+	defer service.hub.mu.Unlock()
+	assert.NotContains(t, service.hub.last, "usdtrub")
+}
+
+func TestSubscribeRates_StoredSnapshot(t *testing.T) {
+	mockRepo := new(MockRepositer)
+	stored := depthWithAsk("100")
+	stored.Spread = entity.MustDecimal("10")
+	mockRepo.On("LatestDepth", mock.Anything, "usdtrub").Return(stored, nil)
+	service := NewService(mockRepo, new(MockRateSource), Config{ServeFromStore: true})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	received := make(chan entity.Depth, 1)
+	go func() {
+		_ = service.SubscribeRates(ctx, "usdtrub", func(dept entity.Depth) error {
+			received <- dept
+			return nil
+		})
+	}()
+
+	// Фоновый опросчик рассылает только изменения, поэтому текущий снимок берется из репозитория
+	select {
+	case dept := <-received:
+		assert.Equal(t, "100", dept.Asks.Price.String())
+		assert.Equal(t, "10", dept.Spread.String())
+	case <-time.After(time.Second):
+		t.Fatal("no snapshot after subscribe")
+	}
+}
+
+func TestSubscribeRates_Close(t *testing.T) {
+	service := storeService(Config{})
+
+	done := make(chan error)
+	go func() {
+		done <- service.SubscribeRates(context.Background(), "usdtrub", func(entity.Depth) error { return nil })
+	}()
+
+	require.Eventually(t, func() bool {
+		service.hub.mu.Lock()
+		defer service.hub.mu.Unlock()
+		return len(service.hub.subs["usdtrub"]) == 1
+	}, time.Second, time.Millisecond)
+
+	// Остановка сервиса завершает подписки, не дожидаясь отключения клиентов
+	service.Close()
+	select {
+	case err := <-done:
+		assert.ErrorIs(t, err, ErrShuttingDown)
+	case <-time.After(time.Second):
+		t.Fatal("subscription is not closed")
+	}
+
+	err := service.SubscribeRates(context.Background(), "usdtrub", func(entity.Depth) error { return nil })
+	assert.ErrorIs(t, err, ErrShuttingDown)
+}
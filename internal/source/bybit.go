@@ -0,0 +1,128 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"rates/internal/entity"
+	"strings"
+)
+
+const (
+	BybitName    = "bybit"
+	BybitBaseURL = "https://api.bybit.com"
+	// bybitDepthLimit - глубина стакана спотового рынка в ответе, максимум биржи 200
+	bybitDepthLimit = "50"
+	// bybitRateLimited - код ответа Bybit при превышении частоты запросов
+	bybitRateLimited = 10006
+)
+
+func init() {
+	Register(BybitName, func(client HTTPDoer) RateSource {
+		return NewBybit(client, BybitBaseURL)
+	})
+}
+
+// Bybit - клиент публичного API спотового рынка биржи Bybit (v5)
+type Bybit struct {
+	client  HTTPDoer
+	baseURL string
+}
+
+func NewBybit(client HTTPDoer, baseURL string) *Bybit {
+	return &Bybit{client: client, baseURL: baseURL}
+}
+
+func (b *Bybit) Name() string {
+	return BybitName
+}
+
+// bybitDepth - ответ /v5/market/orderbook. Уровни стакана передаются парами [цена, объем]
+type bybitDepth struct {
+	RetCode int    `json:"retCode"`
+	RetMsg  string `json:"retMsg"`
+	Result  struct {
+		Asks [][2]entity.Decimal `json:"a"`
+		Bids [][2]entity.Decimal `json:"b"`
+		// Время формирования стакана в миллисекундах
+		Timestamp int64 `json:"ts"`
+	} `json:"result"`
+}
+
+func (b *Bybit) GetDepth(ctx context.Context, market string) (entity.DepthRequest, error) {
+	query := url.Values{
+		"category": {"spot"},
+		"symbol":   {strings.ToUpper(market)},
+		"limit":    {bybitDepthLimit},
+	}
+	endpoint := b.baseURL + "/v5/market/orderbook?" + query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return entity.DepthRequest{}, err
+	}
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		log.Errorf("Error during HTTP request: %v", err)
+		return entity.DepthRequest{}, fmt.Errorf("%w: %w", entity.ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		log.Errorf("Unexpected response from %s: %v", BybitName, err)
+		return entity.DepthRequest{}, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("Error reading response body: %v", err)
+		return entity.DepthRequest{}, fmt.Errorf("%w: %w", entity.ErrUpstreamUnavailable, err)
+	}
+
+	var data bybitDepth
+	if err := json.Unmarshal(body, &data); err != nil {
+		log.Errorf("Error unmarshalling response data: %v", err)
+		return entity.DepthRequest{}, fmt.Errorf("%w: %w", entity.ErrMalformedPayload, err)
+	}
+	// Ошибки запроса Bybit возвращает со статусом 200 и ненулевым retCode
+	switch data.RetCode {
+	case 0:
+	case bybitRateLimited:
+		return entity.DepthRequest{}, fmt.Errorf("%w: %s", entity.ErrRateLimited, data.RetMsg)
+	default:
+		log.Errorf("Unexpected response from %s: code %d: %s", BybitName, data.RetCode, data.RetMsg)
+		return entity.DepthRequest{}, fmt.Errorf("%w: code %d: %s", entity.ErrMalformedPayload, data.RetCode, data.RetMsg)
+	}
+
+	asks, err := bybitOrders(data.Result.Asks)
+	if err != nil {
+		return entity.DepthRequest{}, err
+	}
+	bids, err := bybitOrders(data.Result.Bids)
+	if err != nil {
+		return entity.DepthRequest{}, err
+	}
+	return entity.DepthRequest{
+		Timestamp: data.Result.Timestamp / 1000,
+		Asks:      asks,
+		Bids:      bids,
+		Source:    BybitName,
+	}, nil
+}
+
+// bybitOrders переводит уровни [цена, объем] в формат Garantex, сумма уровня вычисляется
+func bybitOrders(levels [][2]entity.Decimal) ([]entity.Order, error) {
+	orders := make([]entity.Order, 0, len(levels))
+	for _, level := range levels {
+		amount, err := level[0].Mul(level[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", entity.ErrMalformedPayload, err)
+		}
+		orders = append(orders, entity.Order{Price: level[0], Volume: level[1], Amount: amount, Type: "limit"})
+	}
+	return orders, nil
+}
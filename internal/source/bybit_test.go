@@ -0,0 +1,60 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestBybit_GetDepth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/v5/market/orderbook", r.URL.Path)
+		require.Equal(t, "spot", r.URL.Query().Get("category"))
+		require.Equal(t, "BTCUSDT", r.URL.Query().Get("symbol"))
+
+		_, _ = w.Write([]byte(`{"retCode":0,"retMsg":"OK","result":{"s":"BTCUSDT",
+			"a":[["65557.7","0.5"],["65558","1"]],"b":[["65485.47","2"]],"ts":1716863719031,"u":230704}}`))
+	}))
+	defer srv.Close()
+
+	depth, err := NewBybit(srv.Client(), srv.URL).GetDepth(context.Background(), "btcusdt")
+	require.NoError(t, err)
+	// Время стакана приводится к секундам, как у Garantex
+	require.Equal(t, int64(1716863719), depth.Timestamp)
+	require.Len(t, depth.Asks, 2)
+	require.Equal(t, "65557.7", depth.Asks[0].Price.String())
+	require.Equal(t, "32778.85", depth.Asks[0].Amount.String())
+	require.Equal(t, "130970.94", depth.Bids[0].Amount.String())
+	require.Equal(t, BybitName, depth.Source)
+	require.NoError(t, depth.Validate())
+}
+
+func TestBybit_GetDepth_Errors(t *testing.T) {
+	cases := map[string]error{
+		`{"retCode":10006,"retMsg":"Too many visits!","result":{}}`:      entity.ErrRateLimited,
+		`{"retCode":10001,"retMsg":"Not supported symbols","result":{}}`: entity.ErrMalformedPayload,
+		`{"retCode":0,"result":{"a":[["abc","1"]],"b":[],"ts":1}}`:       entity.ErrMalformedPayload,
+		`<html>bad gateway</html>`:                                       entity.ErrMalformedPayload,
+	}
+	for payload, want := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte(payload))
+		}))
+
+		_, err := NewBybit(srv.Client(), srv.URL).GetDepth(context.Background(), "btcusdt")
+		require.ErrorIs(t, err, want, payload)
+		srv.Close()
+	}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer srv.Close()
+	_, err := NewBybit(srv.Client(), srv.URL).GetDepth(context.Background(), "btcusdt")
+	require.ErrorIs(t, err, entity.ErrUpstreamUnavailable)
+}
@@ -0,0 +1,87 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"rates/internal/entity"
+	"rates/internal/infrastructure/metrics"
+	"strings"
+	"time"
+)
+
+// ErrAllSourcesFailed возвращается, если ни один источник из списка не ответил
+var ErrAllSourcesFailed = errors.New("all rate sources failed")
+
+// Failover опрашивает источники по порядку и возвращает первый корректный ответ.
+// Следующий источник используется при ошибке, таймауте или некорректном стакане
+type Failover struct {
+	sources []RateSource
+	timeout time.Duration
+}
+
+// NewFailover создает источник с резервированием. timeout ограничивает одну попытку, 0 - без ограничения
+func NewFailover(timeout time.Duration, sources ...RateSource) *Failover {
+	return &Failover{sources: sources, timeout: timeout}
+}
+
+// Name возвращает имена источников в порядке опроса: "garantex>backup"
+func (f *Failover) Name() string {
+	names := make([]string, 0, len(f.sources))
+	for _, src := range f.sources {
+		names = append(names, src.Name())
+	}
+	return strings.Join(names, ">")
+}
+
+func (f *Failover) GetDepth(ctx context.Context, market string) (entity.DepthRequest, error) {
+	errs := make([]error, 0, len(f.sources))
+	for _, src := range f.sources {
+		data, err := f.try(ctx, src, market)
+		if err == nil {
+			return data, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", src.Name(), err))
+
+		// Запрос отменен клиентом - опрашивать остальные источники бессмысленно
+		if ctx.Err() != nil {
+			break
+		}
+		log.Warnf("Rate source %s failed for %s, trying next: %v", src.Name(), market, err)
+	}
+	return entity.DepthRequest{}, fmt.Errorf("%w: %w", ErrAllSourcesFailed, errors.Join(errs...))
+}
+
+// try выполняет одну попытку и фиксирует доступность источника в метриках
+func (f *Failover) try(ctx context.Context, src RateSource, market string) (entity.DepthRequest, error) {
+	if f.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, f.timeout)
+		defer cancel()
+	}
+
+	data, err := src.GetDepth(ctx, market)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			metrics.StatusSource(src.Name(), "timeout")
+		} else {
+			metrics.StatusSource(src.Name(), "error")
+		}
+		return entity.DepthRequest{}, err
+	}
+	// Пустой или перекрестный стакан тоже повод обратиться к следующему источнику
+	err = data.Validate()
+	if err == nil {
+		err = data.CheckBook()
+	}
+	if err != nil {
+		metrics.StatusSource(src.Name(), "invalid")
+		return entity.DepthRequest{}, err
+	}
+	metrics.StatusSource(src.Name(), "success")
+
+	if data.Source == "" {
+		data.Source = src.Name()
+	}
+	return data, nil
+}
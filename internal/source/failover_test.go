@@ -0,0 +1,77 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/require"
+)
+
+type stubSource struct {
+	name  string
+	depth entity.DepthRequest
+	err   error
+	delay time.Duration
+	calls int
+}
+
+func (s *stubSource) Name() string {
+	return s.name
+}
+
+func (s *stubSource) GetDepth(ctx context.Context, market string) (entity.DepthRequest, error) {
+	s.calls++
+	if s.delay > 0 {
+		select {
+		case <-time.After(s.delay):
+		case <-ctx.Done():
+			return entity.DepthRequest{}, ctx.Err()
+		}
+	}
+	return s.depth, s.err
+}
+
+func validDepth() entity.DepthRequest {
+	return entity.DepthRequest{
+		Timestamp: 1234567890,
+		Asks:      []entity.Order{{Price: entity.MustDecimal("100"), Volume: entity.MustDecimal("1")}},
+		Bids:      []entity.Order{{Price: entity.MustDecimal("90"), Volume: entity.MustDecimal("1")}},
+	}
+}
+
+func TestFailover_GetDepth(t *testing.T) {
+	down := &stubSource{name: "down", err: errors.New("connection refused")}
+	slow := &stubSource{name: "slow", depth: validDepth(), delay: time.Second}
+	// Нулевая цена не проходит проверку стакана
+	invalid := &stubSource{name: "invalid", depth: entity.DepthRequest{
+		Asks: []entity.Order{{Volume: entity.MustDecimal("1")}},
+	}}
+	backup := &stubSource{name: "backup", depth: validDepth()}
+	unused := &stubSource{name: "unused", depth: validDepth()}
+
+	src := NewFailover(10*time.Millisecond, down, slow, invalid, backup, unused)
+	require.Equal(t, "down>slow>invalid>backup>unused", src.Name())
+
+	depth, err := src.GetDepth(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	require.Equal(t, "backup", depth.Source)
+	require.Equal(t, int64(1234567890), depth.Timestamp)
+	require.Equal(t, 1, backup.calls)
+	require.Zero(t, unused.calls)
+}
+
+func TestFailover_AllFailed(t *testing.T) {
+	src := NewFailover(0,
+		&stubSource{name: "a", err: errors.New("bad gateway")},
+		&stubSource{name: "b", err: errors.New("timeout")},
+	)
+
+	_, err := src.GetDepth(context.Background(), "usdtrub")
+	require.ErrorIs(t, err, ErrAllSourcesFailed)
+	require.ErrorContains(t, err, "a: bad gateway")
+	require.ErrorContains(t, err, "b: timeout")
+}
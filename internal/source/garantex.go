@@ -0,0 +1,72 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"rates/internal/entity"
+)
+
+const (
+	GarantexName    = "garantex"
+	GarantexBaseURL = "https://garantex.org"
+)
+
+func init() {
+	Register(GarantexName, func(client HTTPDoer) RateSource {
+		return NewGarantex(client, GarantexBaseURL)
+	})
+}
+
+// Garantex - клиент публичного API биржи Garantex
+type Garantex struct {
+	client  HTTPDoer
+	baseURL string
+}
+
+func NewGarantex(client HTTPDoer, baseURL string) *Garantex {
+	return &Garantex{client: client, baseURL: baseURL}
+}
+
+func (g *Garantex) Name() string {
+	return GarantexName
+}
+
+func (g *Garantex) GetDepth(ctx context.Context, market string) (entity.DepthRequest, error) {
+	endpoint := g.baseURL + "/api/v2/depth?market=" + url.QueryEscape(market)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return entity.DepthRequest{}, err
+	}
+
+	// Get запрос к Garantex для получения стакана по рынку
+	resp, err := g.client.Do(req)
+	if err != nil {
+		log.Errorf("Error during HTTP request: %v", err)
+		return entity.DepthRequest{}, fmt.Errorf("%w: %w", entity.ErrUpstreamUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	if err := checkStatus(resp); err != nil {
+		log.Errorf("Unexpected response from %s: %v", GarantexName, err)
+		return entity.DepthRequest{}, err
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		log.Errorf("Error reading response body: %v", err)
+		return entity.DepthRequest{}, fmt.Errorf("%w: %w", entity.ErrUpstreamUnavailable, err)
+	}
+
+	var data entity.DepthRequest
+	if err := json.Unmarshal(body, &data); err != nil {
+		log.Errorf("Error unmarshalling response data: %v", err)
+		return entity.DepthRequest{}, fmt.Errorf("%w: %w", entity.ErrMalformedPayload, err)
+	}
+	data.Source = GarantexName
+	return data, nil
+}
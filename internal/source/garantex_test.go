@@ -0,0 +1,80 @@
+package source
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rates/internal/entity"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGarantex_GetDepth(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v2/depth", r.URL.Path)
+		require.Equal(t, "usdtrub", r.URL.Query().Get("market"))
+
+		_, _ = w.Write([]byte(`{"timestamp":1234567890,
+			"asks":[{"price":"100","volume":"1","amount":"100","factor":"0","type":"limit"}],
+			"bids":[{"price":"90","volume":"2","amount":"180","factor":"0","type":"limit"}]}`))
+	}))
+	defer srv.Close()
+
+	src := NewGarantex(srv.Client(), srv.URL)
+
+	depth, err := src.GetDepth(context.Background(), "usdtrub")
+	require.NoError(t, err)
+	require.Equal(t, int64(1234567890), depth.Timestamp)
+	require.Len(t, depth.Asks, 1)
+	require.Equal(t, "100", depth.Asks[0].Price.String())
+	require.Equal(t, "180", depth.Bids[0].Amount.String())
+}
+
+func TestGarantex_GetDepth_BadPayload(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`<html>bad gateway</html>`))
+	}))
+	defer srv.Close()
+
+	src := NewGarantex(srv.Client(), srv.URL)
+
+	_, err := src.GetDepth(context.Background(), "usdtrub")
+	require.ErrorIs(t, err, entity.ErrMalformedPayload)
+}
+
+func TestGarantex_GetDepth_Status(t *testing.T) {
+	cases := map[int]error{
+		http.StatusTooManyRequests:     entity.ErrRateLimited,
+		http.StatusServiceUnavailable:  entity.ErrUpstreamUnavailable,
+		http.StatusInternalServerError: entity.ErrUpstreamUnavailable,
+		http.StatusNotFound:            entity.ErrMalformedPayload,
+	}
+	for status, want := range cases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(`{"timestamp":1,"asks":[],"bids":[]}`))
+		}))
+
+		_, err := NewGarantex(srv.Client(), srv.URL).GetDepth(context.Background(), "usdtrub")
+		require.ErrorIs(t, err, want, status)
+		srv.Close()
+	}
+
+	// Сетевая ошибка - источник недоступен
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+	_, err := NewGarantex(srv.Client(), srv.URL).GetDepth(context.Background(), "usdtrub")
+	require.ErrorIs(t, err, entity.ErrUpstreamUnavailable)
+}
+
+func TestNew(t *testing.T) {
+	src, err := New(GarantexName, nil)
+	require.NoError(t, err)
+	require.Equal(t, GarantexName, src.Name())
+
+	_, err = New("unknown", nil)
+	require.Error(t, err)
+	require.Equal(t, []string{BybitName, GarantexName}, Names())
+}
@@ -0,0 +1,90 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"rates/internal/entity"
+	"rates/pkg/logger"
+	"sort"
+	"sync"
+)
+
+var (
+	log = logger.Logger().Named("source").Sugar()
+)
+
+// RateSource - источник котировок (биржа), умеющий отдавать стакан по рынку
+type RateSource interface {
+	Name() string
+	GetDepth(ctx context.Context, market string) (entity.DepthRequest, error)
+}
+
+// HTTPDoer - минимальный HTTP клиент, нужный источникам. Позволяет подменить клиент в тестах
+type HTTPDoer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Factory создает источник поверх переданного HTTP клиента
+type Factory func(client HTTPDoer) RateSource
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]Factory)
+)
+
+// Register регистрирует источник под именем, по которому его можно выбрать из конфигурации
+func Register(name string, factory Factory) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if factory == nil {
+		panic("source: Register factory is nil")
+	}
+	if _, dup := registry[name]; dup {
+		panic("source: Register called twice for source " + name)
+	}
+	registry[name] = factory
+}
+
+// New создает зарегистрированный источник по имени
+func New(name string, client HTTPDoer) (RateSource, error) {
+	mu.RLock()
+	factory, ok := registry[name]
+	mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("unknown rate source %q (registered: %v)", name, Names())
+	}
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return factory(client), nil
+}
+
+// Names возвращает отсортированный список зарегистрированных источников
+func Names() []string {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// checkStatus переводит неуспешный HTTP статус ответа источника в ошибку из entity
+func checkStatus(resp *http.Response) error {
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: status %d", entity.ErrRateLimited, resp.StatusCode)
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return fmt.Errorf("%w: status %d", entity.ErrUpstreamUnavailable, resp.StatusCode)
+	default:
+		return fmt.Errorf("%w: unexpected status %d", entity.ErrMalformedPayload, resp.StatusCode)
+	}
+}
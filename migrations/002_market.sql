@@ -0,0 +1,7 @@
+-- +goose Up
+
+ALTER TABLE history ADD COLUMN IF NOT EXISTS market VARCHAR(20) NOT NULL DEFAULT 'usdtrub';
+
+-- +goose Down
+
+ALTER TABLE history DROP COLUMN IF EXISTS market;
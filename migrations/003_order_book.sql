@@ -0,0 +1,19 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS order_book(
+    id SERIAL PRIMARY KEY,
+    market VARCHAR(20) NOT NULL,
+    transcription_type VARCHAR(4) NOT NULL,
+    level INTEGER NOT NULL,
+    type_price VARCHAR(10) NOT NULL,
+    price DECIMAL(18, 8) NOT NULL,
+    volume DECIMAL(18, 8) NOT NULL,
+    amount DECIMAL(18, 8) NOT NULL,
+    time_stamp_order BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS order_book_market_time_idx ON order_book (market, time_stamp_order);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS order_book;
@@ -0,0 +1,7 @@
+-- +goose Up
+
+CREATE INDEX IF NOT EXISTS history_market_time_idx ON history (market, time_stamp_order);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS history_market_time_idx;
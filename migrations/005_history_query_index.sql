@@ -0,0 +1,7 @@
+-- +goose Up
+
+CREATE INDEX IF NOT EXISTS history_market_type_time_idx ON history (market, transcription_type, time_stamp_order, id);
+
+-- +goose Down
+
+DROP INDEX IF EXISTS history_market_type_time_idx;
@@ -0,0 +1,20 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS snapshots(
+    id BIGSERIAL PRIMARY KEY,
+    market VARCHAR(20) NOT NULL,
+    time_stamp_order BIGINT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+ALTER TABLE history ADD COLUMN IF NOT EXISTS snapshot_id BIGINT REFERENCES snapshots(id);
+ALTER TABLE order_book ADD COLUMN IF NOT EXISTS snapshot_id BIGINT REFERENCES snapshots(id);
+
+CREATE INDEX IF NOT EXISTS history_snapshot_idx ON history (snapshot_id);
+CREATE INDEX IF NOT EXISTS order_book_snapshot_idx ON order_book (snapshot_id);
+
+-- +goose Down
+
+ALTER TABLE order_book DROP COLUMN IF EXISTS snapshot_id;
+ALTER TABLE history DROP COLUMN IF EXISTS snapshot_id;
+DROP TABLE IF EXISTS snapshots;
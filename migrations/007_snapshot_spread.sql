@@ -0,0 +1,11 @@
+-- +goose Up
+
+ALTER TABLE snapshots ADD COLUMN IF NOT EXISTS mid DECIMAL(18, 8);
+ALTER TABLE snapshots ADD COLUMN IF NOT EXISTS spread DECIMAL(18, 8);
+ALTER TABLE snapshots ADD COLUMN IF NOT EXISTS spread_bps DECIMAL(18, 8);
+
+-- +goose Down
+
+ALTER TABLE snapshots DROP COLUMN IF EXISTS spread_bps;
+ALTER TABLE snapshots DROP COLUMN IF EXISTS spread;
+ALTER TABLE snapshots DROP COLUMN IF EXISTS mid;
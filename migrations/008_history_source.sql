@@ -0,0 +1,7 @@
+-- +goose Up
+
+ALTER TABLE history ADD COLUMN IF NOT EXISTS source VARCHAR(50);
+
+-- +goose Down
+
+ALTER TABLE history DROP COLUMN IF EXISTS source;
@@ -0,0 +1,17 @@
+-- +goose Up
+
+CREATE TABLE IF NOT EXISTS api_keys(
+    id BIGSERIAL PRIMARY KEY,
+    client_id VARCHAR(100) NOT NULL,
+    -- sha256 ключа в hex, сам ключ не хранится
+    key_hash CHAR(64) NOT NULL UNIQUE,
+    -- запросов в секунду и размер всплеска, NULL означает значения по умолчанию
+    rate_limit DOUBLE PRECISION,
+    burst INTEGER,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- +goose Down
+
+DROP TABLE IF EXISTS api_keys;
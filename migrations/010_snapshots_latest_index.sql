@@ -0,0 +1,9 @@
+-- +goose Up
+
+-- Последний снимок лучших цен по рынку. У снимков стакана mid не заполняется
+CREATE INDEX IF NOT EXISTS snapshots_market_latest_idx ON snapshots (market, time_stamp_order DESC, id DESC)
+    WHERE mid IS NOT NULL;
+
+-- +goose Down
+
+DROP INDEX IF EXISTS snapshots_market_latest_idx;
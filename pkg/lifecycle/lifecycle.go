@@ -0,0 +1,178 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"time"
+
+	"rates/pkg/logger"
+)
+
+var (
+	log = logger.Logger().Named("lifecycle").Sugar()
+)
+
+// Hook - запуск и остановка одного компонента приложения
+type Hook struct {
+	Name string
+	// Start запускает компонент и возвращает управление, длительная работа выполняется в фоне.
+	// ctx действует до завершения остановки всех компонентов
+	Start func(ctx context.Context) error
+	// Stop останавливает компонент, не дольше срока ctx
+	Stop func(ctx context.Context) error
+	// Timeout ограничивает Stop в пределах общего времени остановки, 0 - без отдельного ограничения
+	Timeout time.Duration
+}
+
+// Lifecycle запускает компоненты в порядке добавления и останавливает в обратном порядке
+type Lifecycle struct {
+	shutdownTimeout time.Duration
+	hooks           []Hook
+	started         int
+	failed          chan error
+}
+
+func New(shutdownTimeout time.Duration) *Lifecycle {
+	return &Lifecycle{
+		shutdownTimeout: shutdownTimeout,
+		failed:          make(chan error, 1),
+	}
+}
+
+// Append добавляет компонент. Компоненты, от которых зависят другие, добавляются раньше
+func (l *Lifecycle) Append(hook Hook) {
+	l.hooks = append(l.hooks, hook)
+}
+
+// Fail сообщает об аварийном завершении компонента и запускает остановку приложения.
+// Учитывается первая ошибка
+func (l *Lifecycle) Fail(err error) {
+	select {
+	case l.failed <- err:
+	default:
+		log.Errorf("Component failed during shutdown: %v", err)
+	}
+}
+
+// Start запускает компоненты по порядку и останавливается на первой ошибке.
+// Уже запущенные компоненты остаются запущенными до Stop
+func (l *Lifecycle) Start(ctx context.Context) error {
+	for i, hook := range l.hooks {
+		if hook.Start != nil {
+			if err := hook.Start(ctx); err != nil {
+				return fmt.Errorf("start %s: %w", hook.Name, err)
+			}
+		}
+		l.started = i + 1
+		log.Infof("Started %s", hook.Name)
+	}
+	return nil
+}
+
+// Stop останавливает запущенные компоненты в обратном порядке и возвращает все ошибки остановки.
+// Компонент, не уложившийся в срок, пропускается с ошибкой context.DeadlineExceeded
+func (l *Lifecycle) Stop(ctx context.Context) error {
+	var errs []error
+	for i := l.started - 1; i >= 0; i-- {
+		hook := l.hooks[i]
+		if hook.Stop == nil {
+			continue
+		}
+		start := time.Now()
+		if err := stopHook(ctx, hook); err != nil {
+			errs = append(errs, fmt.Errorf("stop %s: %w", hook.Name, err))
+			log.Errorf("Failed to stop %s: %v", hook.Name, err)
+			continue
+		}
+		log.Infof("Stopped %s in %s", hook.Name, time.Since(start))
+	}
+	l.started = 0
+	return errors.Join(errs...)
+}
+
+func stopHook(ctx context.Context, hook Hook) error {
+	if hook.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, hook.Timeout)
+		defer cancel()
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		done <- hook.Stop(ctx)
+	}()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run запускает компоненты и ждет сигнала из signals, отмены ctx или аварии компонента,
+// после чего останавливает их в пределах shutdownTimeout. Возвращает причину остановки вместе с ошибками остановки
+func (l *Lifecycle) Run(ctx context.Context, signals ...os.Signal) error {
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	sigs := make(chan os.Signal, 1)
+	if len(signals) > 0 {
+		signal.Notify(sigs, signals...)
+		defer signal.Stop(sigs)
+	}
+
+	cause := l.Start(runCtx)
+	if cause == nil {
+		select {
+		case sig := <-sigs:
+			log.Infof("Received %s, shutting down...", sig)
+		case cause = <-l.failed:
+			log.Errorf("Component failed, shutting down: %v", cause)
+		case <-ctx.Done():
+			log.Info("Context canceled, shutting down...")
+		}
+	}
+
+	stopCtx, stopCancel := context.WithTimeout(context.WithoutCancel(ctx), l.shutdownTimeout)
+	defer stopCancel()
+	return errors.Join(cause, l.Stop(stopCtx))
+}
+
+// ServeHook открывает address при запуске и обслуживает соединения в фоне.
+// Ошибка serve, кроме http.ErrServerClosed, останавливает приложение
+func (l *Lifecycle) ServeHook(name, address string, serve func(net.Listener) error,
+	stop func(ctx context.Context) error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			listener, err := net.Listen("tcp", address)
+			if err != nil {
+				return err
+			}
+			log.Infof("%s listening on %s", name, address)
+			go func() {
+				if err := serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+					l.Fail(fmt.Errorf("%s: %w", name, err))
+				}
+			}()
+			return nil
+		},
+		Stop: stop,
+	}
+}
+
+// ShutdownHTTP дожидается завершения запросов и закрывает оставшиеся соединения по истечении ctx
+func ShutdownHTTP(server *http.Server) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		if err := server.Shutdown(ctx); err != nil {
+			return errors.Join(err, server.Close())
+		}
+		return nil
+	}
+}
@@ -0,0 +1,151 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type recorder struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (r *recorder) add(event string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+}
+
+func (r *recorder) hook(name string, startErr, stopErr error) Hook {
+	return Hook{
+		Name: name,
+		Start: func(ctx context.Context) error {
+			r.add("start " + name)
+			return startErr
+		},
+		Stop: func(ctx context.Context) error {
+			r.add("stop " + name)
+			return stopErr
+		},
+	}
+}
+
+func TestRun_Order(t *testing.T) {
+	rec := &recorder{}
+	app := New(time.Second)
+	app.Append(rec.hook("db", nil, nil))
+	app.Append(rec.hook("grpc", nil, nil))
+	app.Append(rec.hook("health", nil, nil))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	require.NoError(t, app.Run(ctx))
+	require.Equal(t, []string{
+		"start db", "start grpc", "start health",
+		"stop health", "stop grpc", "stop db",
+	}, rec.events)
+}
+
+func TestRun_StartError(t *testing.T) {
+	rec := &recorder{}
+	errBind := errors.New("address already in use")
+	app := New(time.Second)
+	app.Append(rec.hook("db", nil, nil))
+	app.Append(rec.hook("grpc", errBind, nil))
+	app.Append(rec.hook("health", nil, nil))
+
+	// Останавливаются только запущенные компоненты
+	err := app.Run(context.Background())
+	require.ErrorIs(t, err, errBind)
+	require.ErrorContains(t, err, "start grpc")
+	require.Equal(t, []string{"start db", "start grpc", "stop db"}, rec.events)
+}
+
+func TestRun_Fail(t *testing.T) {
+	rec := &recorder{}
+	errServe := errors.New("listener closed")
+	app := New(time.Second)
+	app.Append(rec.hook("db", nil, nil))
+	app.Append(Hook{
+		Name: "grpc",
+		Start: func(ctx context.Context) error {
+			go app.Fail(errServe)
+			return nil
+		},
+	})
+
+	err := app.Run(context.Background())
+	require.ErrorIs(t, err, errServe)
+	require.Equal(t, []string{"start db", "stop db"}, rec.events)
+}
+
+func TestStop_TimeoutAndErrors(t *testing.T) {
+	rec := &recorder{}
+	errClose := errors.New("close failed")
+	hang := make(chan struct{})
+	defer close(hang)
+	app := New(time.Second)
+	app.Append(rec.hook("db", nil, errClose))
+	app.Append(Hook{
+		Name: "grpc",
+		Stop: func(ctx context.Context) error {
+			<-hang
+			return nil
+		},
+		Timeout: 10 * time.Millisecond,
+	})
+	app.Append(rec.hook("health", nil, nil))
+	require.NoError(t, app.Start(context.Background()))
+
+	// Зависший компонент не мешает остановке остальных, ошибки собираются вместе
+	start := time.Now()
+	err := app.Stop(context.Background())
+	require.Less(t, time.Since(start), time.Second)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.ErrorIs(t, err, errClose)
+	require.ErrorContains(t, err, "stop grpc")
+	require.ErrorContains(t, err, "stop db")
+	require.Equal(t, []string{"start db", "start health", "stop health", "stop db"}, rec.events)
+}
+
+func TestServeHook(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	addr := listener.Addr().String()
+	listener.Close()
+
+	server := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})}
+	app := New(time.Second)
+	app.Append(app.ServeHook("metrics", addr, server.Serve, ShutdownHTTP(server)))
+	require.NoError(t, app.Start(context.Background()))
+
+	resp, err := http.Get("http://" + addr)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusNoContent, resp.StatusCode)
+
+	// Штатная остановка сервера не считается аварией
+	require.NoError(t, app.Stop(context.Background()))
+	select {
+	case err := <-app.failed:
+		t.Fatalf("unexpected failure: %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Занятый адрес - ошибка запуска
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer busy.Close()
+	app = New(time.Second)
+	app.Append(app.ServeHook("metrics", busy.Addr().String(), server.Serve, ShutdownHTTP(server)))
+	require.Error(t, app.Start(context.Background()))
+}
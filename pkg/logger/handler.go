@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+type levelPayload struct {
+	Level string `json:"level"`
+}
+
+// LevelHandler показывает уровень логирования по GET и меняет его по PUT или POST с телом {"level":"INFO"}
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var payload levelPayload
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1024)).Decode(&payload); err != nil {
+				writeLevel(w, http.StatusBadRequest, err)
+				return
+			}
+			previous := CurrentLevel()
+			if err := SetLevel(payload.Level); err != nil {
+				writeLevel(w, http.StatusBadRequest, err)
+				return
+			}
+			Logger().Named("logger").Sugar().Infof("Log level changed from %s to %s", previous, CurrentLevel())
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			writeLevel(w, http.StatusMethodNotAllowed, errors.New("method not allowed"))
+			return
+		}
+		writeLevel(w, http.StatusOK, nil)
+	})
+}
+
+func writeLevel(w http.ResponseWriter, code int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err != nil {
+		_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	_ = json.NewEncoder(w).Encode(levelPayload{Level: CurrentLevel()})
+}
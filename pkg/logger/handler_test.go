@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLevelHandler(t *testing.T) {
+	BuildLogger(LevelDebug)
+	defer func() { _ = SetLevel(LevelDebug) }()
+	handler := LevelHandler()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/admin/log-level", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"level":"DEBUG"}`, rec.Body.String())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"info"}`)))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"level":"INFO"}`, rec.Body.String())
+	require.Equal(t, LevelInfo, CurrentLevel())
+
+	// Неизвестный уровень не меняет текущий
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/log-level", strings.NewReader(`{"level":"trace"}`)))
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+	require.Equal(t, LevelInfo, CurrentLevel())
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodDelete, "/admin/log-level", nil))
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
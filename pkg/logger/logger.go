@@ -1,6 +1,8 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"sync"
@@ -20,28 +22,38 @@ var (
 	atomicLevel zap.AtomicLevel
 )
 
+// BuildLogger создает логгер при первом вызове и устанавливает уровень logLevel.
+// Логгеры пакетов создаются при инициализации раньше main, поэтому уровень применяется и к уже созданному логгеру
 func BuildLogger(logLevel string) {
 	once.Do(func() {
 		atomicLevel = zap.NewAtomicLevel()
-		SetLevel(logLevel)
 		encoderCfg := zap.NewProductionEncoderConfig()
 		logger = zap.New(zapcore.NewCore(zapcore.NewJSONEncoder(encoderCfg), os.Stdout, atomicLevel), zap.AddCaller())
 	})
+	if err := SetLevel(logLevel); err != nil {
+		panic(err)
+	}
 }
 
-func SetLevel(logLevel string) {
+// ErrInvalidLevel возвращается для уровня, отличного от DEBUG и INFO
+var ErrInvalidLevel = errors.New("invalid log level")
+
+// SetLevel меняет уровень логирования без пересоздания логгера
+func SetLevel(logLevel string) error {
 	switch strings.ToUpper(logLevel) {
 	case LevelDebug:
 		atomicLevel.SetLevel(zapcore.DebugLevel)
 	case LevelInfo:
 		atomicLevel.SetLevel(zapcore.InfoLevel)
 	default:
-		panic("invalid log level specified for logger")
+		return fmt.Errorf("%w %q: expected %s or %s", ErrInvalidLevel, logLevel, LevelDebug, LevelInfo)
 	}
+	return nil
 }
 
+// CurrentLevel возвращает текущий уровень логирования: DEBUG или INFO
 func CurrentLevel() string {
-	return atomicLevel.String()
+	return strings.ToUpper(atomicLevel.String())
 }
 
 func Logger() *zap.Logger {
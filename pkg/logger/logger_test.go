@@ -0,0 +1,19 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestBuildLogger_AppliesLevelToExistingLogger(t *testing.T) {
+	defer func() { _ = SetLevel(LevelDebug) }()
+
+	// Логгер пакета создан до чтения настроек
+	named := Logger().Named("test")
+	BuildLogger(LevelInfo)
+
+	require.Equal(t, LevelInfo, CurrentLevel())
+	require.False(t, named.Core().Enabled(zapcore.DebugLevel))
+}